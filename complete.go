@@ -0,0 +1,267 @@
+package cliparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompleteCommand is the hidden command of dynamic completion (see Parser.HandleComplete).
+// It is not hinted, so it never appears in help, documents nor completion.
+const CompleteCommand = "__complete"
+
+// CompletionDirective tells the shell how to treat the candidates.
+type CompletionDirective int
+
+const (
+	// CompletionNoSpace does not put a space after the candidate completed.
+	CompletionNoSpace CompletionDirective = 1 << iota
+	// CompletionFiles completes file names as well as the candidates.
+	CompletionFiles
+)
+
+// ValueCompleter returns the candidates of a value beginning with prefix, in the namespace ns.
+// A candidate may have a description after a tab.
+// Candidates not beginning with prefix are dropped.
+type ValueCompleter func(ns []string, prefix string) ([]string, CompletionDirective)
+
+// SetValueCompleter registers the completer of the argument of the option name in the namespace ns.
+// An empty name completes the arguments that are not options nor commands.
+// The completer of an inherited option is used in the descendant namespaces.
+func (p *Parser) SetValueCompleter(name string, c ValueCompleter, optNS ...[]string) {
+	var ns []string
+	if len(optNS) > 0 {
+		ns = optNS[0]
+	}
+	// copies of a Parser share the map
+	completers := make(map[string]ValueCompleter, len(p.completers)+1)
+	for k, v := range p.completers {
+		completers[k] = v
+	}
+	p.completers = completers
+	p.completers[pathKey(append(ns[:len(ns):len(ns)], name))] = c
+}
+
+func (p *Parser) completer(ns []string, name string) ValueCompleter {
+	for i := len(ns); i >= 0; i-- {
+		if c, found := p.completers[pathKey(append(ns[:i:i], name))]; found {
+			return c
+		}
+	}
+	return nil
+}
+
+// PartialContext is what is expected at the cursor of ParsePartial.
+type PartialContext int
+
+const (
+	// ContextCommand is a command, in a namespace that has commands.
+	ContextCommand PartialContext = iota
+	// ContextOptionName is an option name.
+	ContextOptionName
+	// ContextOptionValue is an argument of an option.
+	ContextOptionValue
+	// ContextArg is an argument that is not an option nor a command:
+	// in a namespace without commands, after the first argument, or after --.
+	ContextArg
+)
+
+func (c PartialContext) String() string {
+	switch c {
+	case ContextCommand:
+		return "Command"
+	case ContextOptionName:
+		return "OptionName"
+	case ContextOptionValue:
+		return "OptionValue"
+	case ContextArg:
+		return "Arg"
+	default:
+		return "Unknown"
+	}
+}
+
+// Candidate is a word legal at the cursor.
+type Candidate struct {
+	Word        string
+	Description string
+}
+
+// PartialResult is the result of ParsePartial.
+type PartialResult struct {
+	// Namespace is where the cursor is.
+	Namespace []string
+	Context   PartialContext
+	// Option is the physical name of the option of ContextOptionValue.
+	Option string
+
+	// Prefix is the word at the cursor, with which the candidates begin.
+	Prefix string
+	// Candidates are the words that can replace the word at the cursor.
+	Candidates []Candidate
+	Directive  CompletionDirective
+
+	// valuePrefix is the part of Prefix after = (e.g. ma of --tag=ma), or Prefix.
+	valuePrefix string
+}
+
+// ParsePartial parses args up to the word at cursorIndex, and returns what is expected there.
+// cursorIndex may be len(args) for a new word.
+//
+// Unlike Parse, it does not fail on an option lacking its argument at the cursor,
+// and the built-in help and version (see HintHelp and HintVersion) are not run.
+func (p *Parser) ParsePartial(args []string, cursorIndex int) (PartialResult, error) {
+	if cursorIndex < 0 || cursorIndex > len(args) {
+		return PartialResult{}, fmt.Errorf("cursor index %d out of range [0, %d]", cursorIndex, len(args))
+	}
+	var cur string
+	if cursorIndex < len(args) {
+		cur = args[cursorIndex]
+	}
+
+	g := p.Grammar()
+	s := state{
+		g:        g,
+		node:     g.root,
+		p:        p,
+		args:     args[:cursorIndex],
+		unescape: true,
+		partial:  true,
+	}
+	if err := s.parse(); err != nil {
+		return PartialResult{}, err
+	}
+
+	r := PartialResult{
+		Namespace:   append([]string(nil), s.path()...),
+		Prefix:      cur,
+		valuePrefix: cur,
+	}
+	switch {
+	case s.optName != "" && s.node.testWithArg(s.optName):
+		r.Context = ContextOptionValue
+		r.Option = s.node.toPhysicalName(s.optName)
+
+	case s.argsGiven:
+		r.Context = ContextArg
+
+	case strings.HasPrefix(cur, "-"):
+		r.Context = ContextOptionName
+		if i := strings.Index(cur, "="); i >= 0 {
+			name := strings.TrimPrefix(strings.TrimPrefix(cur[:i], "-"), "-")
+			if s.node.testWithArg(name) {
+				r.Context = ContextOptionValue
+				r.Option = s.node.toPhysicalName(name)
+				r.valuePrefix = cur[i+1:]
+			}
+		}
+
+	case len(s.node.commands()) > 0:
+		r.Context = ContextCommand
+
+	default:
+		r.Context = ContextArg
+	}
+
+	p.candidates(&r, g, s.node)
+	return r, nil
+}
+
+// candidates sets the candidates of r, whose namespace is n of g.
+func (p *Parser) candidates(r *PartialResult, g *Grammar, n *namespace) {
+	wordPrefix := r.Prefix[:len(r.Prefix)-len(r.valuePrefix)] // e.g. --tag=
+	add := func(word, description string) {
+		word = wordPrefix + word
+		if strings.HasPrefix(word, r.Prefix) {
+			r.Candidates = append(r.Candidates, Candidate{Word: word, Description: description})
+		}
+	}
+	addValues := func(name string) {
+		comp := p.completer(r.Namespace, name)
+		if comp == nil {
+			r.Directive |= CompletionFiles
+			return
+		}
+		values, d := comp(r.Namespace, r.valuePrefix)
+		for _, v := range values {
+			word, description := v, ""
+			if i := strings.Index(v, "\t"); i >= 0 {
+				word, description = v[:i], v[i+1:]
+			}
+			add(word, description)
+		}
+		r.Directive |= d
+	}
+
+	switch r.Context {
+	case ContextCommand:
+		subs := withHelpCommand(n.commandSpecs(), g.help)
+		for _, sub := range subs {
+			for _, name := range commandNames(sub, false) {
+				add(name, sub.Description)
+			}
+		}
+		if len(subs) == 0 || p.completer(r.Namespace, "") != nil {
+			addValues("")
+		}
+
+	case ContextOptionName:
+		if n == nil {
+			break
+		}
+		own, inherited, builtin := g.optionsOf(n)
+		for _, o := range append(append(own, inherited...), builtin...) {
+			for _, word := range optionWords(o, false) {
+				add(word, o.Description)
+			}
+		}
+
+	case ContextOptionValue:
+		addValues(r.Option)
+
+	case ContextArg:
+		addValues("")
+	}
+}
+
+// Complete writes the candidates to complete the last of words,
+// which is the command line (without the program name) up to the word being completed.
+//
+// The output is a candidate per line, which may have a description after a tab,
+// and the last line of ":" followed by the CompletionDirective in decimal.
+// Shell scripts are to call the program as "prog __complete words..." (see CompleteCommand and HandleComplete).
+func (p *Parser) Complete(w io.Writer, words []string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	r, err := p.ParsePartial(words, len(words)-1)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range r.Candidates {
+		line := c.Word
+		if c.Description != "" {
+			line += "\t" + c.Description
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, ":%d\n", r.Directive)
+	return err
+}
+
+// HandleComplete runs Complete if args (without the program name) begin with CompleteCommand,
+// with the rest of args as the words.
+// Programs are to call it before parsing, and exit if handled:
+//
+//	if handled, err := p.HandleComplete(os.Stdout, os.Args[1:]); handled {
+//		...
+//	}
+func (p *Parser) HandleComplete(w io.Writer, args []string) (handled bool, err error) {
+	if len(args) == 0 || args[0] != CompleteCommand {
+		return false, nil
+	}
+	return true, p.Complete(w, args[1:])
+}
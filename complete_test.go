@@ -0,0 +1,204 @@
+package cliparser_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+func newDynamicParser() cliparser.Parser {
+	p := newCompletionParser()
+	p.HintDescription("remote", "manage remotes")
+	p.HintWithArg("branch", []string{"remote", "add"})
+	p.HintAlias("b", "branch", []string{"remote", "add"})
+	p.HintWithArg("b", []string{"remote", "add"})
+	p.HintDescription("branch", "the branch to track", []string{"remote", "add"})
+
+	branches := func(ns []string, prefix string) ([]string, cliparser.CompletionDirective) {
+		return []string{"main", "master\tthe old one", "develop"}, cliparser.CompletionNoSpace
+	}
+	p.SetValueCompleter("branch", branches, []string{"remote", "add"})
+	p.SetValueCompleter("", func(ns []string, prefix string) ([]string, cliparser.CompletionDirective) {
+		return []string{"origin", "upstream"}, 0
+	}, []string{"remote", "add"})
+	p.SetValueCompleter("verbose", func(ns []string, prefix string) ([]string, cliparser.CompletionDirective) {
+		return []string{strings.Join(ns, "/")}, 0
+	})
+	return p
+}
+
+func complete(t *testing.T, p *cliparser.Parser, words ...string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := p.Complete(&buf, words)
+	gotwant.TestError(t, err, nil)
+	return buf.String()
+}
+
+func TestComplete(t *testing.T) {
+	t.Run("Commands", func(t *testing.T) {
+		p := newDynamicParser()
+
+		gotwant.Test(t, complete(t, &p, ""), "remote\tmanage remotes\nstatus\nhelp\tshow help of a command\n:0\n")
+		gotwant.Test(t, complete(t, &p, "r"), "remote\tmanage remotes\n:0\n")
+		gotwant.Test(t, complete(t, &p, "-C", "dir", "remote", "r"), "remove\nrm\n:0\n")
+		gotwant.Test(t, complete(t, &p, "help", "re"), "remote\tmanage remotes\n:0\n")
+
+		// no commands
+		gotwant.Test(t, complete(t, &p, "status", ""), ":2\n")
+		gotwant.Test(t, complete(t, &p, "status", "arg", ""), ":2\n")
+		gotwant.Test(t, complete(t, &p, "--", "re"), ":2\n")
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		p := newDynamicParser()
+
+		gotwant.Test(t, complete(t, &p, "-"), "-C\n--verbose\n-v\n--help\t"+"show help\n-h\tshow help\n:0\n")
+		gotwant.Test(t, complete(t, &p, "remote", "add", "--b"), "--branch\tthe branch to track\n:0\n")
+		gotwant.Test(t, complete(t, &p, "remote", "add", "-b", "main", "--v"), "--verbose\n:0\n")
+	})
+
+	t.Run("Values", func(t *testing.T) {
+		p := newDynamicParser()
+
+		gotwant.Test(t, complete(t, &p, "remote", "add", "--branch", "ma"), "main\nmaster\tthe old one\n:1\n")
+		gotwant.Test(t, complete(t, &p, "remote", "add", "-b", ""), "main\nmaster\tthe old one\ndevelop\n:1\n")
+		gotwant.Test(t, complete(t, &p, "remote", "add", "--branch=d"), "--branch=develop\n:1\n")
+		gotwant.Test(t, complete(t, &p, "remote", "add", "-vb", "d"), "develop\n:1\n")
+
+		// arguments
+		gotwant.Test(t, complete(t, &p, "remote", "add", ""), "origin\nupstream\n:0\n")
+		gotwant.Test(t, complete(t, &p, "remote", "add", "origin", "u"), "upstream\n:0\n")
+
+		// no completers
+		gotwant.Test(t, complete(t, &p, "-C", ""), ":2\n")
+		gotwant.Test(t, complete(t, &p, "remote", "add", "-t", ""), ":2\n")
+
+		// inherited
+		p.HintWithArg("verbose")
+		gotwant.Test(t, complete(t, &p, "remote", "add", "--verbose", ""), "remote/add\n:0\n")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		p := newDynamicParser()
+
+		var buf bytes.Buffer
+		err := p.Complete(&buf, []string{"-C", "remote", ""})
+		gotwant.TestError(t, err, "without arguments")
+
+		// built-ins are not run
+		gotwant.Test(t, complete(t, &p, "-h", "st"), "status\n:0\n")
+	})
+
+	t.Run("HandleComplete", func(t *testing.T) {
+		p := newDynamicParser()
+
+		var buf bytes.Buffer
+		handled, err := p.HandleComplete(&buf, []string{cliparser.CompleteCommand, "remote", "r"})
+		gotwant.Test(t, handled, true)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), "remove\nrm\n:0\n")
+
+		buf.Reset()
+		handled, err = p.HandleComplete(&buf, []string{cliparser.CompleteCommand, "-C", "remote", ""})
+		gotwant.Test(t, handled, true)
+		gotwant.TestError(t, err, "without arguments")
+
+		buf.Reset()
+		handled, err = p.HandleComplete(&buf, []string{"remote", cliparser.CompleteCommand})
+		gotwant.Test(t, handled, false)
+		gotwant.TestError(t, err, nil)
+		handled, err = p.HandleComplete(&buf, nil)
+		gotwant.Test(t, handled, false)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.Len(), 0)
+
+		// hidden
+		gotwant.Test(t, complete(t, &p, "__"), ":0\n")
+		buf.Reset()
+		err = p.WriteHelp(&buf, nil, cliparser.HelpOptions{})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, strings.Contains(buf.String(), cliparser.CompleteCommand), false)
+	})
+
+	t.Run("ParsePartial", func(t *testing.T) {
+		p := newDynamicParser()
+		args := []string{"-C", "dir", "remote", "add", "--branch", "ma", "origin"}
+
+		r, err := p.ParsePartial(args, 0)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, len(r.Namespace), 0)
+		gotwant.Test(t, r.Context, cliparser.ContextOptionName)
+		gotwant.Test(t, r.Prefix, "-C")
+		gotwant.Test(t, r.Candidates, []cliparser.Candidate{{Word: "-C"}})
+
+		r, err = p.ParsePartial(args, 1)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, r.Context, cliparser.ContextOptionValue)
+		gotwant.Test(t, r.Option, "C")
+		gotwant.Test(t, len(r.Candidates), 0)
+		gotwant.Test(t, r.Directive, cliparser.CompletionFiles)
+
+		r, err = p.ParsePartial(args, 3)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, r.Namespace, []string{"remote"})
+		gotwant.Test(t, r.Context, cliparser.ContextCommand)
+		gotwant.Test(t, r.Candidates, []cliparser.Candidate{{Word: "add"}})
+
+		r, err = p.ParsePartial(args, 5)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, r.Namespace, []string{"remote", "add"})
+		gotwant.Test(t, r.Context, cliparser.ContextOptionValue)
+		gotwant.Test(t, r.Option, "branch")
+		gotwant.Test(t, r.Candidates, []cliparser.Candidate{{Word: "main"}, {Word: "master", Description: "the old one"}})
+		gotwant.Test(t, r.Directive, cliparser.CompletionNoSpace)
+
+		r, err = p.ParsePartial(args, len(args))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, r.Context, cliparser.ContextArg)
+		gotwant.Test(t, r.Prefix, "")
+		gotwant.Test(t, r.Candidates, []cliparser.Candidate{{Word: "origin"}, {Word: "upstream"}})
+
+		// no commands
+		r, err = p.ParsePartial([]string{"status"}, 1)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, r.Namespace, []string{"status"})
+		gotwant.Test(t, r.Context, cliparser.ContextArg)
+		r, err = p.ParsePartial([]string{"st"}, 0)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, r.Context, cliparser.ContextCommand)
+		gotwant.Test(t, r.Candidates, []cliparser.Candidate{{Word: "status"}})
+
+		// the option lacks its argument at the cursor
+		r, err = p.ParsePartial([]string{"-C"}, 1)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, r.Context, cliparser.ContextOptionValue)
+		gotwant.Test(t, r.Context.String(), "OptionValue")
+
+		_, err = p.ParsePartial(args, len(args)+1)
+		gotwant.TestError(t, err, "out of range")
+	})
+}
+
+func BenchmarkParsePartial(b *testing.B) {
+	p := cliparser.New()
+	for i := 0; i < 2000; i++ {
+		p.HintCommand(fmt.Sprintf("sub%d", i))
+		p.HintAlias(fmt.Sprintf("s%d", i), fmt.Sprintf("sub%d", i))
+		p.HintCommand(fmt.Sprintf("s%d", i))
+		p.HintWithArg(fmt.Sprintf("o%d", i), []string{fmt.Sprintf("sub%d", i)})
+	}
+	args := []string{"sub1000", "-"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.ParsePartial(args, len(args)-1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package cliparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GenerateBashCompletion writes a bash completion script of the program progName to w.
+//
+// The script completes the commands of each namespace, and the options when the word begins with -.
+// It completes files for the argument of an option with an argument and for arguments.
+// Deprecated aliases are recognized but not completed.
+func (p Parser) GenerateBashCompletion(w io.Writer, progName string) error {
+	spec := p.Spec()
+
+	var b strings.Builder
+	fn := "_" + identifier(progName) + "_completion"
+
+	fmt.Fprintf(&b, "# bash completion for %s\n\n", progName)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString(`	local cur word ns="" skip=0 args=0 i commands="" options=""
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		word="${COMP_WORDS[i]}"
+		if [[ $word == "=" ]]; then
+			skip=1
+			continue
+		fi
+		if ((skip)); then
+			skip=0
+			continue
+		fi
+		if ((args)); then
+			continue
+		fi
+		case "$ns:$word" in
+`)
+	nss := spec.completionNamespaces()
+	b.WriteString(shellCases(spec, nss))
+	b.WriteString(`		esac
+	done
+
+	if ((skip || args)); then
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return
+	fi
+
+	case "$ns" in
+`)
+	for _, n := range nss {
+		var cmdWords, optWords []string
+		for _, c := range n.commands {
+			cmdWords = append(cmdWords, commandNames(c, false)...)
+		}
+		for _, o := range n.options {
+			optWords = append(optWords, optionWords(o, false)...)
+		}
+		fmt.Fprintf(&b, "\t%s) commands=%s; options=%s ;;\n",
+			shellQuote(n.key), shellQuote(strings.Join(cmdWords, " ")), shellQuote(strings.Join(optWords, " ")))
+	}
+	b.WriteString(`	esac
+
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "$options" -- "$cur"))
+	elif [[ -n $commands ]]; then
+		COMPREPLY=($(compgen -W "$commands" -- "$cur"))
+	else
+		COMPREPLY=($(compgen -f -- "$cur"))
+	fi
+}
+
+`)
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, shellQuote(progName))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// GenerateZshCompletion writes a zsh completion function _progName to w.
+// It can be put in $fpath as _progName, or be sourced.
+//
+// It completes like GenerateBashCompletion, with the descriptions of the commands and the options.
+func (p Parser) GenerateZshCompletion(w io.Writer, progName string) error {
+	spec := p.Spec()
+
+	var b strings.Builder
+	fn := "_" + identifier(progName)
+
+	fmt.Fprintf(&b, "#compdef %s\n\n", progName)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString(`	local word ns="" skip=0 args=0 i
+	local -a cmds opts
+	for ((i = 2; i < CURRENT; i++)); do
+		word="${words[i]}"
+		if ((skip)); then
+			skip=0
+			continue
+		fi
+		if ((args)); then
+			continue
+		fi
+		case "$ns:$word" in
+`)
+	nss := spec.completionNamespaces()
+	b.WriteString(shellCases(spec, nss))
+	b.WriteString(`		esac
+	done
+
+	if ((skip || args)); then
+		_files
+		return
+	fi
+
+	case "$ns" in
+`)
+	for _, n := range nss {
+		var cmdItems, optItems []string
+		for _, c := range n.commands {
+			for _, name := range commandNames(c, false) {
+				cmdItems = append(cmdItems, shellQuote(zshItem(name, c.Description)))
+			}
+		}
+		for _, o := range n.options {
+			for _, word := range optionWords(o, false) {
+				optItems = append(optItems, shellQuote(zshItem(word, o.Description)))
+			}
+		}
+		fmt.Fprintf(&b, "\t%s)\n", shellQuote(n.key))
+		fmt.Fprintf(&b, "\t\tcmds=(%s)\n", strings.Join(cmdItems, " "))
+		fmt.Fprintf(&b, "\t\topts=(%s)\n", strings.Join(optItems, " "))
+		b.WriteString("\t\t;;\n")
+	}
+	b.WriteString(`	esac
+
+	if [[ $PREFIX == -* ]]; then
+		_describe -t options option opts
+	elif ((${#cmds})); then
+		_describe -t commands command cmds
+	else
+		_files
+	fi
+}
+
+`)
+	fmt.Fprintf(&b, "if [[ \"$funcstack[1]\" == %s ]]; then\n\t%s \"$@\"\nelse\n\tcompdef %s %s\nfi\n",
+		shellQuote(fn), fn, fn, shellQuote(progName))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// GenerateFishCompletion writes fish completions of the program progName to w.
+//
+// A function tracks the namespace of the command line, and complete -c lines are given for each namespace.
+// Grouped short options (-abc) are not followed.
+func (p Parser) GenerateFishCompletion(w io.Writer, progName string) error {
+	spec := p.Spec()
+
+	var b strings.Builder
+	fn := "__fish_" + identifier(progName) + "_ns"
+
+	fmt.Fprintf(&b, "# fish completion for %s\n\n", progName)
+	fmt.Fprintf(&b, "# %s succeeds if the command line is in the namespace $argv[1]\n", fn)
+	fmt.Fprintf(&b, "function %s\n", fn)
+	b.WriteString(`    set -l ns ''
+    set -l skip 0
+    set -l args 0
+    for word in (commandline -opc)[2..-1]
+        if test $skip = 1
+            set skip 0
+            continue
+        end
+        if test $args = 1
+            continue
+        end
+        switch "$ns:$word"
+`)
+	nss := spec.completionNamespaces()
+	for _, n := range nss {
+		for _, tr := range n.transitions {
+			var patterns []string
+			for _, word := range tr.words {
+				patterns = append(patterns, fishQuote(n.key+":"+word))
+			}
+			fmt.Fprintf(&b, "            case %s\n                set ns %s\n", strings.Join(patterns, " "), fishQuote(tr.next))
+		}
+		if len(n.withArgs) > 0 {
+			var patterns []string
+			for _, word := range n.withArgs {
+				patterns = append(patterns, fishQuote(n.key+":"+word))
+			}
+			fmt.Fprintf(&b, "            case %s\n                set skip 1\n", strings.Join(patterns, " "))
+		}
+	}
+	if !spec.DisableDoubleHyphen {
+		b.WriteString("            case '*:--'\n                set args 1\n")
+	}
+	b.WriteString(`            case '*:-*'
+            case '*'
+                set args 1
+        end
+    end
+    test $skip = 0 -a $args = 0 -a "$ns" = "$argv[1]"
+end
+
+`)
+
+	for _, n := range nss {
+		cond := fishQuote(fn + " " + fishQuote(n.key))
+		for _, c := range n.commands {
+			fmt.Fprintf(&b, "complete -c %s -f -n %s -a %s", fishQuote(progName), cond, fishQuote(strings.Join(commandNames(c, false), " ")))
+			if c.Description != "" {
+				fmt.Fprintf(&b, " -d %s", fishQuote(c.Description))
+			}
+			b.WriteString("\n")
+		}
+		for _, o := range n.options {
+			fmt.Fprintf(&b, "complete -c %s -n %s", fishQuote(progName), cond)
+			for _, name := range append([]string{o.Name}, o.Aliases...) {
+				switch {
+				case len(name) == 1:
+					fmt.Fprintf(&b, " -s %s", fishQuote(name))
+				case o.LongName:
+					fmt.Fprintf(&b, " -o %s", fishQuote(name))
+				default:
+					fmt.Fprintf(&b, " -l %s", fishQuote(name))
+				}
+			}
+			if o.WithArg {
+				b.WriteString(" -r")
+			}
+			if o.Description != "" {
+				fmt.Fprintf(&b, " -d %s", fishQuote(o.Description))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shellCases returns the case items of bash and zsh that follow the words of the command line:
+// moving into namespaces, skipping arguments of options, and stopping at arguments.
+func shellCases(spec Spec, nss []completionNS) string {
+	var b strings.Builder
+	for _, n := range nss {
+		for _, tr := range n.transitions {
+			var patterns []string
+			for _, word := range tr.words {
+				patterns = append(patterns, shellQuote(n.key+":"+word))
+			}
+			fmt.Fprintf(&b, "\t\t%s) ns=%s ;;\n", strings.Join(patterns, "|"), shellQuote(tr.next))
+		}
+
+		var patterns []string
+		for _, word := range n.withArgs {
+			patterns = append(patterns, shellQuote(n.key+":"+word))
+			if !spec.NoOptionsGrouped && len(word) == 2 {
+				// the last of grouped short options (-abc)
+				patterns = append(patterns, shellQuote(n.key+":")+"-[!-]*"+shellQuote(word[1:]))
+			}
+		}
+		if len(patterns) > 0 {
+			fmt.Fprintf(&b, "\t\t%s) skip=1 ;;\n", strings.Join(patterns, "|"))
+		}
+	}
+
+	if !spec.DisableDoubleHyphen {
+		b.WriteString("\t\t*:--) args=1 ;;\n")
+	}
+	b.WriteString("\t\t*:-*) ;;\n")
+	b.WriteString("\t\t*) args=1 ;;\n")
+	return b.String()
+}
+
+// completionNS is a namespace as seen by completion scripts.
+type completionNS struct {
+	// key is the namespace joined with spaces.
+	key string
+	// transitions are the commands to move into namespaces.
+	transitions []transition
+	// withArgs are the words of the options with arguments, including deprecated and alternative ones.
+	withArgs []string
+
+	// commands and options are to be completed.
+	commands []CommandSpec
+	options  []OptionSpec
+}
+
+type transition struct {
+	words []string
+	next  string
+}
+
+// completionNamespaces returns all the namespaces of s, depth-first.
+func (s *Spec) completionNamespaces() []completionNS {
+	var list []completionNS
+	s.walk(nil, func(ns []string, c *CommandSpec) {
+		n := completionNS{
+			key:      strings.Join(ns, " "),
+			commands: s.commandsOf(c),
+		}
+
+		for _, sub := range n.commands {
+			next := n.key
+			if c.Lookup([]string{sub.Name}) != nil {
+				next = strings.Join(append(ns[:len(ns):len(ns)], sub.Name), " ")
+			} // else the built-in help command completes commands as if it were not given
+			n.transitions = append(n.transitions, transition{words: commandNames(sub, true), next: next})
+		}
+
+		own, inherited, builtin := s.optionsOf(ns)
+		n.options = append(append(own, inherited...), builtin...)
+		for _, o := range n.options {
+			if o.WithArg {
+				n.withArgs = append(n.withArgs, optionWords(o, true)...)
+			}
+		}
+
+		list = append(list, n)
+	})
+	return list
+}
+
+// walk calls fn with c and its descendants, depth-first.
+func (c *CommandSpec) walk(ns []string, fn func(ns []string, c *CommandSpec)) {
+	fn(ns, c)
+	for i := range c.Commands {
+		c.Commands[i].walk(append(ns[:len(ns):len(ns)], c.Commands[i].Name), fn)
+	}
+}
+
+// commandNames returns the name and the aliases of c, and the deprecated ones if deprecated is true.
+func commandNames(c CommandSpec, deprecated bool) []string {
+	names := append([]string{c.Name}, c.Aliases...)
+	if deprecated {
+		for _, d := range c.Deprecated {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// optionWords returns the words of o as given in the command line (e.g. -t and --tag).
+// If all is true, it also returns the deprecated aliases and the alternative forms of long names.
+func optionWords(o OptionSpec, all bool) []string {
+	names := append([]string{o.Name}, o.Aliases...)
+	if all {
+		for _, d := range o.Deprecated {
+			names = append(names, d.Name)
+		}
+	}
+
+	var words []string
+	for _, name := range names {
+		switch {
+		case len(name) == 1:
+			words = append(words, "-"+name)
+		case all && o.LongName:
+			words = append(words, "-"+name, "--"+name)
+		case o.LongName:
+			words = append(words, "-"+name)
+		default:
+			words = append(words, "--"+name)
+		}
+	}
+	return words
+}
+
+// identifier makes s usable as a shell function name.
+func identifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// zshItem makes an item of _describe.
+func zshItem(name, description string) string {
+	name = strings.Replace(name, ":", `\:`, -1)
+	if description == "" {
+		return name
+	}
+	return name + ":" + description
+}
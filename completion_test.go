@@ -0,0 +1,195 @@
+package cliparser_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shu-go/gotwant"
+)
+
+// completeBash runs the bash completion script with COMP_WORDS set to words, in dir.
+func completeBash(t *testing.T, script, dir string, words ...string) []string {
+	t.Helper()
+
+	var quoted []string
+	for _, w := range words {
+		quoted = append(quoted, "'"+w+"'")
+	}
+	cmd := exec.Command("bash", "-c", script+`
+COMP_WORDS=(`+strings.Join(quoted, " ")+`)
+COMP_CWORD=$((${#COMP_WORDS[@]} - 1))
+_tool_completion
+printf '%s\n' "${COMPREPLY[@]}"
+`)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(out))
+}
+
+// completeZsh runs the zsh completion function with words set to words.
+// _describe and _files are stubbed to print the candidates, unfiltered.
+func completeZsh(t *testing.T, script string, words ...string) []string {
+	t.Helper()
+
+	var quoted []string
+	for _, w := range words {
+		quoted = append(quoted, "'"+w+"'")
+	}
+	out, err := exec.Command("zsh", "-f", "-c", `
+compdef() { :; }
+_describe() { print -rl -- "${(@P)4}"; }
+_files() { print -r -- _files; }
+`+script+`
+words=(`+strings.Join(quoted, " ")+`)
+CURRENT=${#words}
+PREFIX=${words[CURRENT]}
+_tool
+`).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+}
+
+func TestBashCompletion(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+
+	dir, err := ioutil.TempDir("", "completion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "file.txt"), nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := newCompletionParser()
+	var buf bytes.Buffer
+	err = p.GenerateBashCompletion(&buf, "tool")
+	gotwant.TestError(t, err, nil)
+	script := buf.String()
+	gotwant.Test(t, strings.HasSuffix(script, "complete -F _tool_completion 'tool'\n"), true)
+
+	for _, c := range []struct {
+		words []string
+		want  []string
+	}{
+		{words: []string{"tool", ""}, want: []string{"remote", "status", "help"}},
+		{words: []string{"tool", "s"}, want: []string{"status"}},
+		{words: []string{"tool", "-"}, want: []string{"-C", "--verbose", "-v", "--help", "-h"}},
+		{words: []string{"tool", "remote", ""}, want: []string{"add", "remove", "rm", "help"}},
+		{words: []string{"tool", "remote", "--"}, want: []string{"--verbose", "--help"}},
+		{words: []string{"tool", "remote", "add", "-"}, want: []string{"-t", "-mirror", "--verbose", "-v", "--help", "-h"}},
+		{words: []string{"tool", "-v", "remote", "a"}, want: []string{"add"}},
+		{words: []string{"tool", "help", "re"}, want: []string{"remote"}},
+
+		// arguments of options
+		{words: []string{"tool", "-C", ""}, want: []string{"file.txt"}},
+		{words: []string{"tool", "-C", "-"}, want: []string{}},
+		{words: []string{"tool", "-vC", ""}, want: []string{"file.txt"}},
+		{words: []string{"tool", "-C", "remote", ""}, want: []string{"remote", "status", "help"}},
+		{words: []string{"tool", "-C", "=", "dir", "re"}, want: []string{"remote"}},
+		{words: []string{"tool", "remote", "add", "-t", "main", "-"}, want: []string{"-t", "-mirror", "--verbose", "-v", "--help", "-h"}},
+
+		// deprecated aliases are recognized but not completed
+		{words: []string{"tool", "remote", "de"}, want: []string{}},
+		{words: []string{"tool", "remote", "delete", "-"}, want: []string{"--verbose", "-v", "--help", "-h"}},
+
+		// arguments
+		{words: []string{"tool", "arg", "re"}, want: []string{}},
+		{words: []string{"tool", "status", ""}, want: []string{"file.txt"}},
+		{words: []string{"tool", "--", "-"}, want: []string{}},
+	} {
+		got := completeBash(t, script, dir, c.words...)
+		gotwant.Test(t, got, c.want, gotwant.Desc(strings.Join(c.words, " ")))
+	}
+}
+
+func TestZshCompletion(t *testing.T) {
+	p := newCompletionParser()
+	p.HintDescription("remote", "manage remotes")
+	p.HintDescription("C", "run in DIR: the directory")
+
+	var buf bytes.Buffer
+	err := p.GenerateZshCompletion(&buf, "tool")
+	gotwant.TestError(t, err, nil)
+	script := buf.String()
+
+	for _, want := range []string{
+		"#compdef tool\n\n_tool() {\n",
+		"\t\t':remote') ns='remote' ;;\n",
+		"\t\t'remote:remove'|'remote:rm'|'remote:delete') ns='remote remove' ;;\n",
+		"\t\t':-C'|':'-[!-]*'C') skip=1 ;;\n",
+		"\t\tcmds=('remote:manage remotes' 'status' 'help:show help of a command')\n",
+		"\t\topts=('-C:run in DIR: the directory' '--verbose' '-v' '--help:show help' '-h:show help')\n",
+		"\t\tcmds=('add' 'remove' 'rm' 'help:show help of a command')\n",
+		"\tcompdef _tool 'tool'\n",
+	} {
+		gotwant.Test(t, strings.Contains(script, want), true, gotwant.Desc(want))
+	}
+
+	if _, err := exec.LookPath("zsh"); err != nil {
+		t.Skip("zsh not found")
+	}
+
+	out, err := exec.Command("zsh", "-n", "-c", script).CombinedOutput()
+	gotwant.TestError(t, err, nil, gotwant.Desc(string(out)))
+
+	for _, c := range []struct {
+		words []string
+		want  []string
+	}{
+		{words: []string{"tool", ""}, want: []string{"remote:manage remotes", "status", "help:show help of a command"}},
+		{words: []string{"tool", "-"}, want: []string{"-C:run in DIR: the directory", "--verbose", "-v", "--help:show help", "-h:show help"}},
+		{words: []string{"tool", "remote", ""}, want: []string{"add", "remove", "rm", "help:show help of a command"}},
+		{words: []string{"tool", "-v", "remote", ""}, want: []string{"add", "remove", "rm", "help:show help of a command"}},
+		{words: []string{"tool", "-C", ""}, want: []string{"_files"}},
+		{words: []string{"tool", "-C", "remote", ""}, want: []string{"remote:manage remotes", "status", "help:show help of a command"}},
+		{words: []string{"tool", "status", ""}, want: []string{"_files"}},
+	} {
+		got := completeZsh(t, script, c.words...)
+		gotwant.Test(t, got, c.want, gotwant.Desc(strings.Join(c.words, " ")))
+	}
+}
+
+func TestFishCompletion(t *testing.T) {
+	p := newCompletionParser()
+	p.HintDescription("remote", "manage remotes")
+	p.HintDescription("t", "track 'BRANCH'", []string{"remote", "add"})
+
+	var buf bytes.Buffer
+	err := p.GenerateFishCompletion(&buf, "tool")
+	gotwant.TestError(t, err, nil)
+	script := buf.String()
+
+	for _, want := range []string{
+		"function __fish_tool_ns\n",
+		"            case ':remote'\n                set ns 'remote'\n",
+		"            case 'remote:remove' 'remote:rm' 'remote:delete'\n                set ns 'remote remove'\n",
+		"            case ':-C'\n                set skip 1\n",
+		`complete -c 'tool' -f -n '__fish_tool_ns \'\'' -a 'remote' -d 'manage remotes'` + "\n",
+		`complete -c 'tool' -f -n '__fish_tool_ns \'remote\'' -a 'remove rm'` + "\n",
+		`complete -c 'tool' -n '__fish_tool_ns \'\'' -s 'C' -r` + "\n",
+		`complete -c 'tool' -n '__fish_tool_ns \'remote add\'' -s 't' -r -d 'track \'BRANCH\''` + "\n",
+		`complete -c 'tool' -n '__fish_tool_ns \'remote add\'' -o 'mirror'` + "\n",
+		`complete -c 'tool' -n '__fish_tool_ns \'remote\'' -l 'verbose' -s 'v'` + "\n",
+	} {
+		gotwant.Test(t, strings.Contains(script, want), true, gotwant.Desc(want))
+	}
+
+	if _, err := exec.LookPath("fish"); err == nil {
+		out, err := exec.Command("fish", "--no-execute", "-c", script).CombinedOutput()
+		gotwant.TestError(t, err, nil, gotwant.Desc(string(out)))
+	}
+}
@@ -0,0 +1,215 @@
+package cliparser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Config is option values from a config file, to fill in the options not given in the command line.
+type Config []ConfigValue
+
+// ConfigValue is a value of an option in a namespace.
+type ConfigValue struct {
+	Namespace []string
+	Name      string
+	Value     string
+}
+
+// ConfigError is a problem of a config found by Parser.SetConfig.
+type ConfigError struct {
+	Namespace []string
+	Name      string
+	Problem   string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("config: namespace %v: %q %s", e.Namespace, e.Name, e.Problem)
+}
+
+// ConfigErrors is a list of ConfigError.
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, ce := range e {
+		msgs = append(msgs, ce.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ReadConfigJSON reads a config from JSON.
+// Objects are namespaces keyed by commands, and the others are values of options:
+//
+//	{"C": "dir", "remote": {"add": {"track": "main", "tags": ["v1", "v2"]}}}
+//
+// Values are strings, numbers or booleans. Arrays give the option more than once.
+// Keys are sorted in each object.
+func ReadConfigJSON(r io.Reader) (Config, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := c.addJSON(nil, obj); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) addJSON(ns []string, obj map[string]interface{}) error {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := obj[k].(type) {
+		case map[string]interface{}:
+			if err := c.addJSON(append(ns[:len(ns):len(ns)], k), v); err != nil {
+				return err
+			}
+
+		case []interface{}:
+			for _, e := range v {
+				s, ok := jsonScalar(e)
+				if !ok {
+					return fmt.Errorf("config: namespace %v: %q must be an array of strings, numbers or booleans", ns, k)
+				}
+				*c = append(*c, ConfigValue{Namespace: ns, Name: k, Value: s})
+			}
+
+		default:
+			s, ok := jsonScalar(v)
+			if !ok {
+				return fmt.Errorf("config: namespace %v: %q must be a string, a number or a boolean", ns, k)
+			}
+			*c = append(*c, ConfigValue{Namespace: ns, Name: k, Value: s})
+		}
+	}
+	return nil
+}
+
+func jsonScalar(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// ReadConfigINI reads a config from INI.
+// Sections are namespaces whose commands are separated by spaces, and keys before any section are of the root:
+//
+//	C = dir
+//	[remote add]
+//	track = main
+//
+// Lines beginning with ; or # are comments. Values may be quoted with ".
+// A key given more than once gives the option more than once.
+func ReadConfigINI(r io.Reader) (Config, error) {
+	var c Config
+	var ns []string
+
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineno == 1 {
+			line = strings.TrimPrefix(line, "\ufeff") // BOM
+		}
+
+		switch {
+		case line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#"):
+			continue
+
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("config: line %d: unclosed section", lineno)
+			}
+			ns = strings.Fields(line[1 : len(line)-1])
+
+		default:
+			i := strings.Index(line, "=")
+			if i < 0 {
+				return nil, fmt.Errorf("config: line %d: missing =", lineno)
+			}
+			key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+			if key == "" {
+				return nil, fmt.Errorf("config: line %d: missing key", lineno)
+			}
+			if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+				value = value[1 : len(value)-1]
+			}
+			c = append(c, ConfigValue{Namespace: ns, Name: key, Value: value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetConfig sets the config to fill in the options not given in the command line nor by environment variables (see HintEnv).
+// An inherited option given in an ancestor namespace is regarded as given.
+// Their components have the source SourceConfig.
+//
+// Commands and options in c may be aliases. They must be hinted before,
+// otherwise SetConfig reports all of the unknown ones at once as ConfigErrors and the config is not set.
+func (p *Parser) SetConfig(c Config) error {
+	g := p.Grammar()
+
+	var errs ConfigErrors
+	add := func(e ConfigError) {
+		for _, prev := range errs {
+			if prev.Name == e.Name && equalNS(prev.Namespace, e.Namespace) && prev.Problem == e.Problem {
+				return
+			}
+		}
+		errs = append(errs, e)
+	}
+
+	var resolved Config
+	for _, v := range c {
+		node := g.root
+		var ns []string
+		for _, name := range v.Namespace {
+			if !node.testCommand(name) {
+				add(ConfigError{Namespace: ns, Name: name, Problem: "is not a command"})
+				node = nil
+				break
+			}
+			name = node.toPhysicalName(name)
+			node = node.child(name)
+			ns = append(ns, name)
+		}
+		if node == nil {
+			continue
+		}
+
+		if n := node.optionNS(v.Name); n == nil || !n.declares(v.Name) {
+			add(ConfigError{Namespace: ns, Name: v.Name, Problem: "is not an option"})
+			continue
+		}
+		resolved = append(resolved, ConfigValue{Namespace: ns, Name: node.toPhysicalName(v.Name), Value: v.Value})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	p.config = resolved
+	p.grammar = nil
+	return nil
+}
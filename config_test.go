@@ -0,0 +1,123 @@
+package cliparser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("ReadConfigJSON", func(t *testing.T) {
+		c, err := cliparser.ReadConfigJSON(strings.NewReader(`{
+  "C": "dir",
+  "remote": {"add": {"t": ["main", "dev"], "depth": 1, "force": true}}
+}`))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, c, cliparser.Config{
+			{Name: "C", Value: "dir"},
+			{Namespace: []string{"remote", "add"}, Name: "depth", Value: "1"},
+			{Namespace: []string{"remote", "add"}, Name: "force", Value: "true"},
+			{Namespace: []string{"remote", "add"}, Name: "t", Value: "main"},
+			{Namespace: []string{"remote", "add"}, Name: "t", Value: "dev"},
+		})
+
+		_, err = cliparser.ReadConfigJSON(strings.NewReader(`{"C": null}`))
+		gotwant.TestError(t, err, `config: namespace []: "C" must be a string, a number or a boolean`)
+		_, err = cliparser.ReadConfigJSON(strings.NewReader(`{"remote": {"t": [{}]}}`))
+		gotwant.TestError(t, err, `config: namespace [remote]: "t" must be an array`)
+		_, err = cliparser.ReadConfigJSON(strings.NewReader(`["C"]`))
+		gotwant.TestError(t, err, "cannot unmarshal array")
+	})
+
+	t.Run("ReadConfigINI", func(t *testing.T) {
+		c, err := cliparser.ReadConfigINI(strings.NewReader("\ufeff; comment\nC = dir\n\n[remote add]\n# comment\nt = main\nt=\"dev branch\"\nempty =\n"))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, c, cliparser.Config{
+			{Name: "C", Value: "dir"},
+			{Namespace: []string{"remote", "add"}, Name: "t", Value: "main"},
+			{Namespace: []string{"remote", "add"}, Name: "t", Value: "dev branch"},
+			{Namespace: []string{"remote", "add"}, Name: "empty", Value: ""},
+		})
+
+		_, err = cliparser.ReadConfigINI(strings.NewReader("[remote\n"))
+		gotwant.TestError(t, err, "config: line 1: unclosed section")
+		_, err = cliparser.ReadConfigINI(strings.NewReader("C = dir\nremote\n"))
+		gotwant.TestError(t, err, "config: line 2: missing =")
+		_, err = cliparser.ReadConfigINI(strings.NewReader("= dir\n"))
+		gotwant.TestError(t, err, "config: line 1: missing key")
+	})
+
+	t.Run("SetConfig", func(t *testing.T) {
+		p := newRemoteParser()
+		p.HintAlias("tag", "t", []string{"remote", "add"})
+		p.HintInherited("C")
+
+		err := p.SetConfig(cliparser.Config{
+			{Name: "x"},
+			{Namespace: []string{"remote", "unknown"}, Name: "t"},
+			{Namespace: []string{"remote", "unknown"}, Name: "u"},
+			{Namespace: []string{"remote"}, Name: "t"},
+		})
+		gotwant.Test(t, err, cliparser.ConfigErrors{
+			{Name: "x", Problem: "is not an option"},
+			{Namespace: []string{"remote"}, Name: "unknown", Problem: "is not a command"},
+			{Namespace: []string{"remote"}, Name: "t", Problem: "is not an option"},
+		})
+		gotwant.TestError(t, err, `config: namespace []: "x" is not an option; config: namespace [remote]: "unknown" is not a command; config: namespace [remote]: "t" is not an option`)
+
+		c, err := cliparser.ReadConfigINI(strings.NewReader("C = dir\n[remote rm]\nC = dir2\n[remote add]\ntag = main\ntag = dev\n"))
+		gotwant.TestError(t, err, nil)
+		err = p.SetConfig(c)
+		gotwant.TestError(t, err, nil)
+
+		p.Feed([]string{"remote", "add", "origin"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "C", Arg: "dir", Source: cliparser.SourceConfig})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "add"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "origin"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "t", Arg: "main", Source: cliparser.SourceConfig})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "t", Arg: "dev", Source: cliparser.SourceConfig})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		// the deeper namespace wins
+		p.Reset()
+		p.Feed([]string{"remote", "rm", "x"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "C", Arg: "dir", Source: cliparser.SourceConfig})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remove"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "x"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "C", Arg: "dir2", Source: cliparser.SourceConfig})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		// the command line wins, even if given in an ancestor namespace
+		p.Reset()
+		p.Feed([]string{"-C", "given", "remote", "rm", "x"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "C", Arg: "given"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remove"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "x"})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		// environment variables win
+		p.HintEnv("C", "APP_C")
+		p.SetEnvLookup(func(key string) (string, bool) {
+			return "env", key == "APP_C"
+		})
+		p.Reset()
+		p.Feed([]string{"status"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "C", Arg: "env", Source: cliparser.SourceEnv})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "status"})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+		gotwant.Test(t, cliparser.SourceConfig.String(), "Config")
+	})
+}
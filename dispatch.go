@@ -0,0 +1,196 @@
+package cliparser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Handler handles a command.
+type Handler func(ctx context.Context, inv *Invocation) error
+
+// Hook runs before or after a Handler. comps are the components given at the level of the hook.
+type Hook func(ctx context.Context, inv *Invocation, comps []Component) error
+
+// Invocation is a parsed command line passed to a Handler.
+type Invocation struct {
+	// Path is the deepest command given. It is empty for the root.
+	Path []string
+	// Components are all the components parsed.
+	Components []Component
+}
+
+// Level returns the components given in the namespace Path[:depth],
+// that is, between the depth-th command and the next one.
+func (inv *Invocation) Level(depth int) []Component {
+	start := 0
+	for i, c := range inv.Components {
+		if c.Type != Command {
+			continue
+		}
+		if depth == 0 {
+			return inv.Components[start:i]
+		}
+		depth--
+		start = i + 1
+	}
+	if depth > 0 {
+		return nil
+	}
+	return inv.Components[start:]
+}
+
+// Options returns the options of all the levels.
+func (inv *Invocation) Options() []Component {
+	var opts []Component
+	for _, c := range inv.Components {
+		if c.Type == Option {
+			opts = append(opts, c)
+		}
+	}
+	return opts
+}
+
+// Option returns the argument of the option (by its physical name) given last.
+func (inv *Invocation) Option(name string) (arg string, found bool) {
+	for _, c := range inv.Components {
+		if c.Type == Option && c.Name == name {
+			arg, found = c.Arg, true
+		}
+	}
+	return arg, found
+}
+
+// Args returns the arguments that are not options nor commands.
+func (inv *Invocation) Args() []string {
+	var args []string
+	for _, c := range inv.Components {
+		if c.Type == Arg {
+			args = append(args, c.Arg)
+		}
+	}
+	return args
+}
+
+// NoHandlerError is returned by Dispatcher.Run when the command has no handler.
+type NoHandlerError struct {
+	Path []string
+}
+
+func (e *NoHandlerError) Error() string {
+	return fmt.Sprintf("no handler for command %q", strings.Join(e.Path, " "))
+}
+
+// SubcommandRequiredError is returned by Dispatcher.Run when the command has no handler but subcommands.
+type SubcommandRequiredError struct {
+	Path        []string
+	Subcommands []string
+}
+
+func (e *SubcommandRequiredError) Error() string {
+	return fmt.Sprintf("command %q requires a subcommand: %s", strings.Join(e.Path, " "), strings.Join(e.Subcommands, ", "))
+}
+
+// Dispatcher runs handlers of commands parsed by a Parser.
+type Dispatcher struct {
+	parser   *Parser
+	handlers map[string]Handler
+	preRuns  map[string][]Hook
+	postRuns map[string][]Hook
+}
+
+// NewDispatcher makes a Dispatcher using the Parser p.
+func NewDispatcher(p *Parser) *Dispatcher {
+	return &Dispatcher{
+		parser:   p,
+		handlers: make(map[string]Handler),
+		preRuns:  make(map[string][]Hook),
+		postRuns: make(map[string][]Hook),
+	}
+}
+
+func pathKey(path []string) string {
+	return strings.Join(path, "\x00")
+}
+
+// Handle registers the handler of the command whose namespace is ns, as HintCommand does. Omitting ns is the root.
+func (d *Dispatcher) Handle(h Handler, optNS ...[]string) {
+	var ns []string
+	if len(optNS) > 0 {
+		ns = optNS[0]
+	}
+	d.handlers[pathKey(ns)] = h
+}
+
+// PreRun registers a hook that runs before the handler of the command whose namespace is ns or its descendants.
+// Hooks run from the root to the deepest command, in order of registration.
+// If a hook returns an error, Run stops and returns it.
+func (d *Dispatcher) PreRun(h Hook, optNS ...[]string) {
+	var ns []string
+	if len(optNS) > 0 {
+		ns = optNS[0]
+	}
+	key := pathKey(ns)
+	d.preRuns[key] = append(d.preRuns[key], h)
+}
+
+// PostRun registers a hook that runs after the handler of the command whose namespace is ns or its descendants succeeded.
+// Hooks run from the deepest command to the root, in order of registration.
+// If a hook returns an error, Run stops and returns it.
+func (d *Dispatcher) PostRun(h Hook, optNS ...[]string) {
+	var ns []string
+	if len(optNS) > 0 {
+		ns = optNS[0]
+	}
+	key := pathKey(ns)
+	d.postRuns[key] = append(d.postRuns[key], h)
+}
+
+// Run parses args (without the program name), and calls the handler of the deepest command given,
+// with the hooks of its levels.
+// If the command has no handler, Run returns *SubcommandRequiredError or *NoHandlerError.
+func (d *Dispatcher) Run(ctx context.Context, args []string) error {
+	p := d.parser
+	p.Reset()
+	p.Feed(args)
+	if err := p.Parse(); err != nil {
+		return err
+	}
+
+	inv := &Invocation{}
+	for c := p.GetComponent(); c != nil; c = p.GetComponent() {
+		if c.Type == Command {
+			inv.Path = append(inv.Path, c.Name)
+		}
+		inv.Components = append(inv.Components, *c)
+	}
+
+	h, found := d.handlers[pathKey(inv.Path)]
+	if !found {
+		if subs := p.Grammar().lookup(inv.Path).commands(); len(subs) > 0 {
+			return &SubcommandRequiredError{Path: inv.Path, Subcommands: subs}
+		}
+		return &NoHandlerError{Path: inv.Path}
+	}
+
+	for depth := 0; depth <= len(inv.Path); depth++ {
+		for _, hook := range d.preRuns[pathKey(inv.Path[:depth])] {
+			if err := hook(ctx, inv, inv.Level(depth)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := h(ctx, inv); err != nil {
+		return err
+	}
+
+	for depth := len(inv.Path); depth >= 0; depth-- {
+		for _, hook := range d.postRuns[pathKey(inv.Path[:depth])] {
+			if err := hook(ctx, inv, inv.Level(depth)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package cliparser_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+func TestDispatcher(t *testing.T) {
+	t.Run("Run", func(t *testing.T) {
+		p := newRemoteParser()
+		d := cliparser.NewDispatcher(&p)
+
+		var got *cliparser.Invocation
+		h := func(ctx context.Context, inv *cliparser.Invocation) error {
+			got = inv
+			return nil
+		}
+		d.Handle(h)
+		d.Handle(h, []string{"remote", "add"})
+		d.Handle(func(ctx context.Context, inv *cliparser.Invocation) error {
+			return errors.New("failed to remove")
+		}, []string{"remote", "remove"})
+
+		err := d.Run(context.Background(), []string{"-C", "dir", "remote", "-v", "add", "-t", "main", "origin", "url"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, got.Path, []string{"remote", "add"})
+		gotwant.Test(t, got.Args(), []string{"origin", "url"})
+		gotwant.Test(t, got.Options(), []cliparser.Component{
+			{Type: cliparser.Option, Name: "C", Arg: "dir"},
+			{Type: cliparser.Option, Name: "v", Arg: "true"},
+			{Type: cliparser.Option, Name: "t", Arg: "main"},
+		})
+		arg, found := got.Option("t")
+		gotwant.Test(t, arg, "main")
+		gotwant.Test(t, found, true)
+		_, found = got.Option("x")
+		gotwant.Test(t, found, false)
+
+		gotwant.Test(t, got.Level(0), []cliparser.Component{{Type: cliparser.Option, Name: "C", Arg: "dir"}})
+		gotwant.Test(t, got.Level(1), []cliparser.Component{{Type: cliparser.Option, Name: "v", Arg: "true"}})
+		gotwant.Test(t, len(got.Level(2)), 3)
+		gotwant.Test(t, got.Level(3), ([]cliparser.Component)(nil))
+
+		err = d.Run(context.Background(), []string{"-C", "dir", "arg"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, len(got.Path), 0)
+		gotwant.Test(t, got.Args(), []string{"arg"})
+
+		err = d.Run(context.Background(), []string{"remote", "rm", "origin"})
+		gotwant.TestError(t, err, "failed to remove")
+
+		err = d.Run(context.Background(), []string{"-C"})
+		gotwant.TestError(t, err, "without arguments")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		p := newRemoteParser()
+		d := cliparser.NewDispatcher(&p)
+
+		err := d.Run(context.Background(), []string{"remote"})
+		gotwant.Test(t, err, &cliparser.SubcommandRequiredError{Path: []string{"remote"}, Subcommands: []string{"add", "remove"}})
+		gotwant.TestError(t, err, `command "remote" requires a subcommand: add, remove`)
+
+		err = d.Run(context.Background(), []string{"status"})
+		gotwant.Test(t, err, &cliparser.NoHandlerError{Path: []string{"status"}})
+		gotwant.TestError(t, err, `no handler for command "status"`)
+
+		var nh *cliparser.NoHandlerError
+		gotwant.Test(t, errors.As(err, &nh), true)
+	})
+
+	t.Run("Hooks", func(t *testing.T) {
+		p := newRemoteParser()
+		d := cliparser.NewDispatcher(&p)
+
+		var log []string
+		hook := func(name string) cliparser.Hook {
+			return func(ctx context.Context, inv *cliparser.Invocation, comps []cliparser.Component) error {
+				log = append(log, name+fmt.Sprint(len(comps)))
+				if ctx.Value(ctxKey{}) == name {
+					return errors.New("abort at " + name)
+				}
+				return nil
+			}
+		}
+		d.PreRun(hook("pre-root"))
+		d.PreRun(hook("pre-root2"))
+		d.PreRun(hook("pre-remote"), []string{"remote"})
+		d.PreRun(hook("pre-add"), []string{"remote", "add"})
+		d.PreRun(hook("pre-status"), []string{"status"})
+		d.PostRun(hook("post-root"))
+		d.PostRun(hook("post-remote"), []string{"remote"})
+		d.PostRun(hook("post-add"), []string{"remote", "add"})
+		d.Handle(func(ctx context.Context, inv *cliparser.Invocation) error {
+			log = append(log, "handler")
+			if ctx.Value(ctxKey{}) == "handler" {
+				return errors.New("abort at handler")
+			}
+			return nil
+		}, []string{"remote", "add"})
+
+		args := []string{"-C", "dir", "remote", "add", "-t", "main", "origin"}
+
+		err := d.Run(context.Background(), args)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, log, []string{"pre-root1", "pre-root21", "pre-remote0", "pre-add2", "handler", "post-add2", "post-remote0", "post-root1"})
+
+		log = nil
+		err = d.Run(context.WithValue(context.Background(), ctxKey{}, "pre-remote"), args)
+		gotwant.TestError(t, err, "abort at pre-remote")
+		gotwant.Test(t, log, []string{"pre-root1", "pre-root21", "pre-remote0"})
+
+		log = nil
+		err = d.Run(context.WithValue(context.Background(), ctxKey{}, "handler"), args)
+		gotwant.TestError(t, err, "abort at handler")
+		gotwant.Test(t, log, []string{"pre-root1", "pre-root21", "pre-remote0", "pre-add2", "handler"})
+
+		log = nil
+		err = d.Run(context.WithValue(context.Background(), ctxKey{}, "post-remote"), args)
+		gotwant.TestError(t, err, "abort at post-remote")
+		gotwant.Test(t, log, []string{"pre-root1", "pre-root21", "pre-remote0", "pre-add2", "handler", "post-add2", "post-remote0"})
+
+		// no hooks without a handler
+		log = nil
+		err = d.Run(context.Background(), []string{"status"})
+		gotwant.TestError(t, err, "no handler")
+		gotwant.Test(t, len(log), 0)
+	})
+}
+
+type ctxKey struct{}
@@ -0,0 +1,208 @@
+// Package external provides git-style external subcommands for cliparser.
+//
+// When "tool foo" is parsed and foo is not a hinted command,
+// an executable named "tool-foo" found in the directories is used as the command.
+// For a namespace, the names are joined: "tool remote foo" finds "tool-remote-foo".
+package external
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/shu-go/cliparser"
+)
+
+// Command is an external command.
+type Command struct {
+	// Name is the subcommand name (foo of tool-foo).
+	Name string
+	// Path is the path of the executable.
+	Path string
+}
+
+// Finder discovers external commands.
+// A Finder remembers the commands given to a Parser by Hint and Resolver, so use a Finder per Parser.
+type Finder struct {
+	// Prefix is the program name (tool of tool-foo).
+	Prefix string
+	// Dirs are searched in order. If nil, $PATH is used.
+	Dirs []string
+
+	given map[string]bool // keys of the namespaces of the commands given by Hint and Resolver
+}
+
+// Match is an external command found by Finder.Parse.
+type Match struct {
+	Command
+
+	// Namespace is where the command is found.
+	Namespace []string
+	// Args are the rest of the command line, to be forwarded to the command.
+	Args []string
+}
+
+func (f Finder) dirs() []string {
+	if f.Dirs != nil {
+		return f.Dirs
+	}
+	return filepath.SplitList(os.Getenv("PATH"))
+}
+
+func (f Finder) prefix(ns []string) string {
+	return strings.Join(append([]string{f.Prefix}, ns...), "-") + "-"
+}
+
+// List returns the external commands in the namespace ns, sorted by name.
+// If a name is found in multiple directories, the first one is used.
+func (f Finder) List(ns []string) []Command {
+	prefix := f.prefix(ns)
+
+	found := make(map[string]bool)
+	var list []Command
+	for _, dir := range f.dirs() {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range files {
+			name, ok := commandName(fi, prefix)
+			if !ok || found[name] {
+				continue
+			}
+			found[name] = true
+			list = append(list, Command{Name: name, Path: filepath.Join(dir, fi.Name())})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Lookup finds the external command name in the namespace ns.
+func (f Finder) Lookup(ns []string, name string) (Command, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return Command{}, false
+	}
+
+	file := f.prefix(ns) + name
+	if runtime.GOOS == "windows" {
+		file += ".exe"
+	}
+	for _, dir := range f.dirs() {
+		path := filepath.Join(dir, file)
+		if fi, err := os.Stat(path); err == nil && executable(fi) {
+			return Command{Name: name, Path: path}, true
+		}
+	}
+	return Command{}, false
+}
+
+// executable reports whether the file is an executable.
+// On Windows, it is tested by its extension instead.
+func executable(fi os.FileInfo) bool {
+	return !fi.IsDir() && (runtime.GOOS == "windows" || fi.Mode()&0111 != 0)
+}
+
+// commandName returns the subcommand name of the file if it is an executable with the prefix.
+func commandName(fi os.FileInfo, prefix string) (string, bool) {
+	if !executable(fi) {
+		return "", false
+	}
+
+	name := fi.Name()
+	if runtime.GOOS == "windows" {
+		ext := filepath.Ext(name)
+		if !strings.EqualFold(ext, ".exe") {
+			return "", false
+		}
+		name = name[:len(name)-len(ext)]
+	}
+
+	if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+		return "", false
+	}
+	return name[len(prefix):], true
+}
+
+func key(ns []string, name string) string {
+	return strings.Join(append(ns[:len(ns):len(ns)], name), "\x00")
+}
+
+// give remembers the command name in ns is given to a Parser.
+func (f *Finder) give(ns []string, name string) {
+	if f.given == nil {
+		f.given = make(map[string]bool)
+	}
+	f.given[key(ns, name)] = true
+}
+
+// Hint gives p the external commands in the namespace ns as commands,
+// so that they are listed in the grammar for help and completion.
+// Commands already hinted are left as they are.
+func (f *Finder) Hint(p *cliparser.Parser, ns []string) {
+	spec := p.Spec()
+	for _, c := range f.List(ns) {
+		if spec.Lookup(append(ns[:len(ns):len(ns)], c.Name)) != nil {
+			continue
+		}
+		p.HintCommand(c.Name, ns)
+		f.give(ns, c.Name)
+	}
+}
+
+// Resolver returns a command resolver (see cliparser.Parser.SetCommandResolver) of external commands.
+// found is called with the namespace and the command when one is resolved.
+func (f *Finder) Resolver(found func(ns []string, c Command)) func(ns []string, word string) (*cliparser.CommandSpec, bool) {
+	return func(ns []string, word string) (*cliparser.CommandSpec, bool) {
+		c, ok := f.Lookup(ns, word)
+		if !ok {
+			return nil, false
+		}
+		f.give(ns, c.Name)
+		if found != nil {
+			found(ns, c)
+		}
+		return &cliparser.CommandSpec{Name: c.Name}, true
+	}
+}
+
+// Parse parses the command line fed to p, and stops at an external command.
+// Hinted commands take precedence over external ones,
+// except the ones given by Hint or resolved by a previous Parse.
+//
+// It returns the components before the external command, and the external command if found.
+// Parse sets the command resolver of p.
+func (f *Finder) Parse(p *cliparser.Parser) ([]cliparser.Component, *Match, error) {
+	p.SetCommandResolver(f.Resolver(nil))
+
+	var match *Match
+	var ns []string
+	var comps []cliparser.Component
+	err := p.ParseFunc(func(c cliparser.Component) error {
+		if c.Type == cliparser.Command {
+			if f.given[key(ns, c.Name)] {
+				if ext, ok := f.Lookup(ns, c.Name); ok {
+					match = &Match{Command: ext, Namespace: ns}
+					return cliparser.ErrStop
+				}
+			}
+			ns = append(ns[:len(ns):len(ns)], c.Name)
+		}
+		comps = append(comps, c)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if match != nil {
+		match.Args = append([]string(nil), p.Rest()...)
+	}
+	return comps, match, nil
+}
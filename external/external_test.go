@@ -0,0 +1,191 @@
+package external_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/cliparser/external"
+	"github.com/shu-go/gotwant"
+)
+
+func writeScript(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, []byte("#!/bin/sh\necho "+name+" \"$@\"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExternal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts are not executable on windows")
+	}
+
+	dir1, err := ioutil.TempDir("", "external")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir1)
+	dir2, err := ioutil.TempDir("", "external")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir2)
+
+	foo1 := writeScript(t, dir1, "tool-foo")
+	writeScript(t, dir2, "tool-foo")
+	bar := writeScript(t, dir2, "tool-bar")
+	add := writeScript(t, dir2, "tool-remote-add")
+	writeScript(t, dir2, "other-baz")
+	writeScript(t, dir2, "tool-")
+	err = ioutil.WriteFile(filepath.Join(dir2, "tool-noexec"), nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := external.Finder{Prefix: "tool", Dirs: []string{dir1, filepath.Join(dir1, "none"), dir2}}
+
+	t.Run("List", func(t *testing.T) {
+		gotwant.Test(t, f.List(nil), []external.Command{
+			{Name: "bar", Path: bar},
+			{Name: "foo", Path: foo1},
+			{Name: "remote-add", Path: add},
+		})
+		gotwant.Test(t, f.List([]string{"remote"}), []external.Command{
+			{Name: "add", Path: add},
+		})
+	})
+
+	t.Run("Lookup", func(t *testing.T) {
+		c, ok := f.Lookup(nil, "foo")
+		gotwant.Test(t, ok, true)
+		gotwant.Test(t, c, external.Command{Name: "foo", Path: foo1})
+
+		_, ok = f.Lookup(nil, "noexec")
+		gotwant.Test(t, ok, false)
+		_, ok = f.Lookup(nil, "baz")
+		gotwant.Test(t, ok, false)
+	})
+
+	t.Run("Hint", func(t *testing.T) {
+		f := external.Finder{Prefix: f.Prefix, Dirs: f.Dirs}
+
+		p := cliparser.New()
+		p.HintCommand("remote")
+		f.Hint(&p, nil)
+		f.Hint(&p, []string{"remote"})
+
+		spec := p.Spec()
+		gotwant.Test(t, len(spec.Commands), 4)
+		gotwant.Test(t, spec.Lookup([]string{"remote", "add"}) != nil, true)
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		f := external.Finder{Prefix: f.Prefix, Dirs: f.Dirs}
+
+		p := cliparser.New()
+		p.HintCommand("bar") // builtin
+		p.HintCommand("remote")
+		p.HintWithArg("C")
+
+		p.Feed([]string{"-C", "dir", "foo", "-x", "--y=z", "arg"})
+		comps, m, err := f.Parse(&p)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, comps, []cliparser.Component{
+			{Type: cliparser.Option, Name: "C", Arg: "dir"},
+		})
+		gotwant.Test(t, m, &external.Match{
+			Command: external.Command{Name: "foo", Path: foo1},
+			Args:    []string{"-x", "--y=z", "arg"},
+		})
+
+		out, err := exec.Command(m.Path, m.Args...).Output()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, strings.TrimSpace(string(out)), "tool-foo -x --y=z arg")
+
+		p.Reset()
+		p.Feed([]string{"remote", "add", "origin"})
+		comps, m, err = f.Parse(&p)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, comps, []cliparser.Component{
+			{Type: cliparser.Command, Name: "remote"},
+		})
+		gotwant.Test(t, m, &external.Match{
+			Command:   external.Command{Name: "add", Path: add},
+			Namespace: []string{"remote"},
+			Args:      []string{"origin"},
+		})
+
+		p.Reset()
+		p.Feed([]string{"bar", "baz"})
+		comps, m, err = f.Parse(&p)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, comps, []cliparser.Component{
+			{Type: cliparser.Command, Name: "bar"},
+			{Type: cliparser.Arg, Arg: "baz"},
+		})
+		gotwant.Test(t, m, (*external.Match)(nil))
+
+		p.Reset()
+		p.Feed([]string{"-C"})
+		_, _, err = f.Parse(&p)
+		gotwant.TestError(t, err, "without arguments")
+	})
+
+	t.Run("ParseTwice", func(t *testing.T) {
+		f := external.Finder{Prefix: f.Prefix, Dirs: f.Dirs}
+
+		p := cliparser.New()
+		for i := 0; i < 2; i++ {
+			p.Reset()
+			p.Feed([]string{"foo", "-x"})
+			comps, m, err := f.Parse(&p)
+			gotwant.TestError(t, err, nil, gotwant.Desc(fmt.Sprint(i)))
+			gotwant.Test(t, len(comps), 0, gotwant.Desc(fmt.Sprint(i)))
+			gotwant.Test(t, m, &external.Match{
+				Command: external.Command{Name: "foo", Path: foo1},
+				Args:    []string{"-x"},
+			}, gotwant.Desc(fmt.Sprint(i)))
+		}
+	})
+
+	t.Run("HintAndParse", func(t *testing.T) {
+		f := external.Finder{Prefix: f.Prefix, Dirs: f.Dirs}
+
+		p := cliparser.New()
+		p.HintCommand("bar") // builtin
+		p.HintDescription("bar", "external command tool-foo")
+		f.Hint(&p, nil)
+		p.HintDescription("foo", "run foo")
+		gotwant.Test(t, p.Spec().Commands[1], cliparser.CommandSpec{Name: "foo", Description: "run foo"})
+
+		p.Feed([]string{"foo", "-x"})
+		comps, m, err := f.Parse(&p)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, len(comps), 0)
+		gotwant.Test(t, m, &external.Match{
+			Command: external.Command{Name: "foo", Path: foo1},
+			Args:    []string{"-x"},
+		})
+
+		p.Reset()
+		p.Feed([]string{"bar", "-x"})
+		comps, m, err = f.Parse(&p)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, comps, []cliparser.Component{
+			{Type: cliparser.Command, Name: "bar"},
+			{Type: cliparser.Option, Name: "x", Arg: "true"},
+		})
+		gotwant.Test(t, m, (*external.Match)(nil))
+	})
+}
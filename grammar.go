@@ -0,0 +1,507 @@
+package cliparser
+
+import (
+	"sort"
+	"strings"
+)
+
+// Grammar is a compiled set of hints and modes.
+// It is immutable and safe for concurrent use by multiple goroutines.
+type Grammar struct {
+	root                *namespace
+	optsMaybeGrouped    bool
+	doubleHyphenEnabled bool
+	help                bool
+	version             bool
+	lookupEnv           func(key string) (string, bool)
+	defaults            bool // any options from environment variables or a config
+}
+
+// namespace is an index of the hints given for a namespace.
+type namespace struct {
+	path     []string
+	parent   *namespace
+	names    map[string]nameFlags
+	aliases  map[string]string
+	children map[string]*namespace
+
+	defaultCmd string
+	deprecated map[string]*deprecation
+	env        []envBinding
+	config     []ConfigValue // by physical names
+
+	// for completion, in the order hinted, as Parser.Spec describes them
+	commandList  []string             // physical names
+	optionList   []string             // physical names
+	listed       map[string]nameFlags // commandName or optionName, by physical names
+	aliasList    map[string][]string  // by physical names, including deprecated ones
+	descriptions map[string]string    // by physical names
+}
+
+// envBinding binds an option (by its physical name) to an environment variable.
+type envBinding struct {
+	name     string
+	variable string
+}
+
+type deprecation struct {
+	message   string
+	removedIn string
+}
+
+type nameFlags uint8
+
+const (
+	commandName nameFlags = 1 << iota
+	withArgName
+	longNameName
+	optionName
+	inheritedName
+
+	optionFlags = withArgName | longNameName | optionName
+)
+
+// Grammar compiles hints and modes given so far.
+// The Grammar is not affected by hints given afterwards.
+func (p *Parser) Grammar() *Grammar {
+	if p.grammar != nil {
+		return p.grammar
+	}
+
+	root := newNamespace(nil)
+	for _, h := range p.hints {
+		ns := root
+		for _, name := range h.namespace {
+			ns = ns.ensureChild(name)
+		}
+
+		switch h.typ {
+		case aliasHint:
+			alias, name := splitAlias(h.name)
+			if _, found := ns.aliases[alias]; !found {
+				ns.aliases[alias] = name
+			}
+		case commandHint:
+			ns.names[h.name] |= commandName
+		case withArgHint:
+			ns.names[h.name] |= withArgName
+		case longNameHint:
+			ns.names[h.name] |= longNameName
+		case optionHint:
+			ns.names[h.name] |= optionName
+		case deprecatedHint:
+			if ns.deprecated == nil {
+				ns.deprecated = make(map[string]*deprecation)
+			}
+			if _, found := ns.deprecated[h.name]; !found {
+				ns.deprecated[h.name] = &deprecation{message: h.text, removedIn: h.removedIn}
+			}
+		case defaultCommandHint:
+			if ns.defaultCmd == "" {
+				ns.defaultCmd = h.name
+			}
+		}
+	}
+	// after all aliases are known, and commands before the others
+	for _, h := range p.hints {
+		if h.typ == commandHint {
+			root.lookup(h.namespace).list(h)
+		}
+	}
+	for _, h := range p.hints {
+		if h.typ != commandHint {
+			root.lookup(h.namespace).list(h)
+		}
+		switch h.typ {
+		case inheritedHint:
+			root.lookup(h.namespace).inherit(h.name)
+		case envHint:
+			ns := root.lookup(h.namespace)
+			ns.bindEnv(ns.toPhysicalName(h.name), h.text)
+		}
+	}
+	if p.envPrefix != "" {
+		root.bindEnvPrefix(p.envPrefix)
+	}
+	for _, v := range p.config {
+		ns := root
+		for _, name := range v.Namespace {
+			ns = ns.ensureChild(name)
+		}
+		ns.config = append(ns.config, v)
+	}
+	// every command has its namespace, so that parsing never loses its path
+	root.ensureCommandChildren()
+
+	p.grammar = &Grammar{
+		root:                root,
+		optsMaybeGrouped:    p.optsMaybeGrouped,
+		doubleHyphenEnabled: p.doubleHyphenEnabled,
+		help:                p.help,
+		version:             p.version,
+		lookupEnv:           p.lookupEnv,
+		defaults:            root.anyDefaults(),
+	}
+	return p.grammar
+}
+
+// Parse parses args (without the program name) and returns the components,
+// and the warnings of deprecated aliases given (see HintDeprecatedAlias).
+func (g *Grammar) Parse(args []string) ([]Component, []Warning, error) {
+	return g.ParseInto(make([]Component, 0, 8), args)
+}
+
+// ParseInto is Parse reusing dst for the result.
+// The components are appended to dst[:0], so that it does not allocate if dst has enough capacity.
+func (g *Grammar) ParseInto(dst []Component, args []string) ([]Component, []Warning, error) {
+	s := state{
+		g:        g,
+		node:     g.root,
+		args:     args,
+		unescape: true,
+		result:   dst,
+	}
+	err := s.parse()
+	return s.result, s.warnings, err
+}
+
+func newNamespace(path []string) *namespace {
+	return &namespace{
+		path:     path,
+		names:    make(map[string]nameFlags),
+		aliases:  make(map[string]string),
+		children: make(map[string]*namespace),
+
+		listed:       make(map[string]nameFlags),
+		aliasList:    make(map[string][]string),
+		descriptions: make(map[string]string),
+	}
+}
+
+func (n *namespace) ensureChild(name string) *namespace {
+	child, found := n.children[name]
+	if !found {
+		path := make([]string, len(n.path)+1)
+		copy(path, n.path)
+		path[len(n.path)] = name
+		child = newNamespace(path)
+		child.parent = n
+		n.children[name] = child
+	}
+	return child
+}
+
+func (n *namespace) ensureCommandChildren() {
+	for name, flags := range n.names {
+		if flags&commandName != 0 {
+			n.ensureChild(n.toPhysicalName(name))
+		}
+	}
+	for _, child := range n.children {
+		child.ensureCommandChildren()
+	}
+}
+
+// lookup returns the namespace of the path ns, or nil if no hints are given for it.
+func (g *Grammar) lookup(ns []string) *namespace {
+	return g.root.lookup(ns)
+}
+
+func (n *namespace) lookup(ns []string) *namespace {
+	curr := n
+	for _, name := range ns {
+		curr = curr.child(name)
+	}
+	return curr
+}
+
+// inherit marks the option name and its aliases inherited.
+// If name is an alias, the option it points to is inherited.
+func (n *namespace) inherit(name string) {
+	if physical, found := n.aliases[name]; found {
+		name = physical
+	}
+	n.names[name] |= inheritedName
+	for alias, physical := range n.aliases {
+		if physical == name {
+			n.names[alias] |= inheritedName
+		}
+	}
+}
+
+// declares reports whether n has hints of the option name.
+func (n *namespace) declares(name string) bool {
+	if n.names[name]&optionFlags != 0 {
+		return true
+	}
+	_, found := n.aliases[name]
+	return found
+}
+
+// optionNS returns the namespace whose hints apply to the option name:
+// n itself, or the nearest ancestor that makes it inherited.
+func (n *namespace) optionNS(name string) *namespace {
+	if n == nil || n.declares(name) {
+		return n
+	}
+	for curr := n.parent; curr != nil; curr = curr.parent {
+		if curr.names[name]&inheritedName != 0 {
+			return curr
+		}
+	}
+	return n
+}
+
+// hasDefaults reports whether n has options from environment variables or a config. n may be nil.
+func (n *namespace) hasDefaults() bool {
+	return n != nil && (len(n.env) > 0 || len(n.config) > 0)
+}
+
+// anyDefaults reports whether n or its descendants have options from environment variables or a config.
+func (n *namespace) anyDefaults() bool {
+	if n.hasDefaults() {
+		return true
+	}
+	for _, child := range n.children {
+		if child.anyDefaults() {
+			return true
+		}
+	}
+	return false
+}
+
+// pathOrNil returns the path of n. n may be nil.
+func (n *namespace) pathOrNil() []string {
+	if n == nil {
+		return nil
+	}
+	return n.path
+}
+
+// bindEnv binds the option name to the environment variable unless it is bound.
+func (n *namespace) bindEnv(name, variable string) {
+	for _, b := range n.env {
+		if b.name == name {
+			return
+		}
+	}
+	n.env = append(n.env, envBinding{name: name, variable: variable})
+}
+
+// bindEnvPrefix binds the options of n and its descendants to environment variables like PREFIX_NS_NAME.
+func (n *namespace) bindEnvPrefix(prefix string) {
+	var names []string
+	for name, flags := range n.names {
+		if _, isAlias := n.aliases[name]; flags&optionFlags != 0 && !isAlias {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		words := append(append([]string{prefix}, n.path...), name)
+		variable := strings.Map(func(r rune) rune {
+			if 'a' <= r && r <= 'z' {
+				return r - 'a' + 'A'
+			}
+			if 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+				return r
+			}
+			return '_'
+		}, strings.Join(words, "_"))
+		n.bindEnv(name, variable)
+	}
+
+	for _, child := range n.children {
+		child.bindEnvPrefix(prefix)
+	}
+}
+
+// child returns the sub-namespace. n may be nil.
+func (n *namespace) child(name string) *namespace {
+	if n == nil {
+		return nil
+	}
+	return n.children[name]
+}
+
+func (n *namespace) toPhysicalName(alias string) string {
+	n = n.optionNS(alias)
+	if n == nil {
+		return alias
+	}
+	if name, found := n.aliases[alias]; found {
+		return name
+	}
+	return alias
+}
+
+// deprecation returns the deprecation of the alias, or nil.
+func (n *namespace) deprecation(alias string) *deprecation {
+	n = n.optionNS(alias)
+	if n == nil {
+		return nil
+	}
+	return n.deprecated[alias]
+}
+
+// defaultCommand returns the physical name of the default command, or "".
+func (n *namespace) defaultCommand() string {
+	if n == nil || n.defaultCmd == "" {
+		return ""
+	}
+	return n.toPhysicalName(n.defaultCmd)
+}
+
+func (n *namespace) testCommand(name string) bool {
+	return n != nil && n.names[name]&commandName != 0
+}
+
+func (n *namespace) testWithArg(name string) bool {
+	n = n.optionNS(name)
+	return n != nil && n.names[name]&withArgName != 0
+}
+
+func (n *namespace) testLongName(name string) bool {
+	n = n.optionNS(name)
+	return n != nil && n.names[name]&longNameName != 0
+}
+
+// commands returns the physical names of the commands, sorted.
+func (n *namespace) commands() []string {
+	if n == nil {
+		return nil
+	}
+
+	var names []string
+	for name, flags := range n.names {
+		if flags&commandName != 0 {
+			names = appendUnique(names, n.toPhysicalName(name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// list lists the command or the option of h for completion.
+// Command hints are to be listed before the others.
+func (n *namespace) list(h hint) {
+	switch h.typ {
+	case commandHint:
+		n.listAs(n.physical(h.name), commandName)
+
+	case aliasHint:
+		alias, name := splitAlias(h.name)
+		if n.aliases[alias] != name {
+			return // the first alias wins
+		}
+		if n.names[alias]&commandName == 0 && n.names[name]&commandName == 0 {
+			n.listAs(name, optionName)
+		}
+		n.aliasList[name] = appendUnique(n.aliasList[name], alias)
+
+	case withArgHint, longNameHint, optionHint:
+		if _, isAlias := n.aliases[h.name]; !isAlias {
+			n.listAs(h.name, optionName)
+		}
+
+	case deprecatedHint:
+		if n.names[h.name]&commandName == 0 && n.names[n.physical(h.name)]&commandName == 0 {
+			n.listAs(n.physical(h.name), optionName)
+		}
+
+	case descriptionHint:
+		if h.name == "" {
+			return
+		}
+		name := n.physical(h.name)
+		if n.listed[name]&commandName == 0 {
+			n.listAs(name, optionName)
+		}
+		n.descriptions[name] = h.text
+
+	case envHint, metavarHint, inheritedHint:
+		n.listAs(n.physical(h.name), optionName)
+	}
+}
+
+// listAs lists the physical name as a command or an option unless listed.
+func (n *namespace) listAs(name string, flag nameFlags) {
+	if n.listed[name]&flag != 0 {
+		return
+	}
+	n.listed[name] |= flag
+	if flag == commandName {
+		n.commandList = append(n.commandList, name)
+	} else {
+		n.optionList = append(n.optionList, name)
+	}
+}
+
+// physical returns the name that the alias points to in n, not in the ancestors.
+func (n *namespace) physical(alias string) string {
+	if name, found := n.aliases[alias]; found {
+		return name
+	}
+	return alias
+}
+
+// commandSpecs returns the commands of n, as Parser.Spec describes them.
+// n may be nil.
+func (n *namespace) commandSpecs() []CommandSpec {
+	if n == nil {
+		return nil
+	}
+
+	cmds := make([]CommandSpec, 0, len(n.commandList))
+	for _, name := range n.commandList {
+		c := CommandSpec{Name: name, Description: n.descriptions[name]}
+		for _, alias := range n.aliasList[name] {
+			if n.deprecated[alias] != nil {
+				c.Deprecated = append(c.Deprecated, DeprecatedAlias{Name: alias})
+			} else {
+				c.Aliases = append(c.Aliases, alias)
+			}
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds
+}
+
+// optionSpecs returns the options of n, as Parser.Spec describes them.
+// n may be nil.
+func (n *namespace) optionSpecs() []OptionSpec {
+	if n == nil {
+		return nil
+	}
+
+	opts := make([]OptionSpec, 0, len(n.optionList))
+	for _, name := range n.optionList {
+		o := OptionSpec{
+			Name:        name,
+			Description: n.descriptions[name],
+			WithArg:     n.names[name]&withArgName != 0,
+			LongName:    n.names[name]&longNameName != 0,
+			Inherited:   n.names[name]&inheritedName != 0,
+		}
+		for _, alias := range n.aliasList[name] {
+			if n.deprecated[alias] != nil {
+				o.Deprecated = append(o.Deprecated, DeprecatedAlias{Name: alias})
+			} else {
+				o.Aliases = append(o.Aliases, alias)
+			}
+		}
+		opts = append(opts, o)
+	}
+	return opts
+}
+
+// optionsOf returns the options of n, the options inherited from its ancestors,
+// and the built-in options not overridden by them, as Spec.optionsOf does.
+func (g *Grammar) optionsOf(n *namespace) (own, inherited, builtin []OptionSpec) {
+	var levels [][]OptionSpec
+	for curr := n; curr != nil; curr = curr.parent {
+		levels = append(levels, curr.optionSpecs())
+	}
+	return collectOptions(levels, g.help, g.version)
+}
@@ -0,0 +1,243 @@
+package cliparser_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+func TestGrammar(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("sub")
+		p.HintWithArg("b", []string{"sub"})
+		g := p.Grammar()
+
+		args := []string{"-a", "sub", "-b=ccc", "ddd"}
+		cc, _, err := g.Parse(args)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, cc, []cliparser.Component{
+			{Type: cliparser.Option, Name: "a", Arg: "true"},
+			{Type: cliparser.Command, Name: "sub"},
+			{Type: cliparser.Option, Name: "b", Arg: "ccc"},
+			{Type: cliparser.Arg, Arg: "ddd"},
+		})
+		gotwant.Test(t, args, []string{"-a", "sub", "-b=ccc", "ddd"})
+
+		_, _, err = g.Parse([]string{"sub", "-b"})
+		gotwant.TestError(t, err, "without arguments")
+	})
+
+	t.Run("Immutable", func(t *testing.T) {
+		p := cliparser.New()
+		ns := []string{"sub"}
+		p.HintCommand("sub")
+		p.HintWithArg("b", ns)
+		g := p.Grammar()
+		gotwant.Test(t, p.Grammar() == g, true)
+
+		ns[0] = "other"
+		p.HintCommand("subsub", []string{"sub"})
+		gotwant.Test(t, p.Grammar() == g, false)
+
+		cc, _, err := g.Parse([]string{"sub", "-b", "subsub"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, cc, []cliparser.Component{
+			{Type: cliparser.Command, Name: "sub"},
+			{Type: cliparser.Option, Name: "b", Arg: "subsub"},
+		})
+	})
+
+	t.Run("Copy", func(t *testing.T) {
+		p := cliparser.New()
+		ns := []string{"a"}
+		p.HintCommand("a")
+		p.HintWithArg("o", ns)
+		ns[0] = "other" // hints keep their own namespaces
+
+		p2 := p
+		p2.HintCommand("b")
+		p.HintCommand("c")
+
+		cc, _, err := p.Grammar().Parse([]string{"a", "-o", "x", "b", "c"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, cc, []cliparser.Component{
+			{Type: cliparser.Command, Name: "a"},
+			{Type: cliparser.Option, Name: "o", Arg: "x"},
+			{Type: cliparser.Arg, Arg: "b"},
+			{Type: cliparser.Arg, Arg: "c"},
+		})
+
+		cc, _, err = p2.Grammar().Parse([]string{"b"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, cc, []cliparser.Component{{Type: cliparser.Command, Name: "b"}})
+		cc, _, err = p2.Grammar().Parse([]string{"c"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, cc, []cliparser.Component{{Type: cliparser.Arg, Arg: "c"}})
+	})
+
+	t.Run("CopyParsed", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintDeprecatedAlias("o", "opt", "", "")
+		p.Feed([]string{"-a"})
+
+		p2 := p
+		p.Feed([]string{"-b", "-o"})
+		p2.Feed([]string{"-x", "-y"})
+		gotwant.TestError(t, p.Parse(), nil)
+		gotwant.TestError(t, p2.Parse(), nil)
+		gotwant.Test(t, len(p.Warnings()), 1)
+		gotwant.Test(t, len(p2.Warnings()), 0)
+
+		for _, name := range []string{"a", "b", "opt"} {
+			gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: name, Arg: "true"})
+		}
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+		for _, name := range []string{"a", "x", "y"} {
+			gotwant.Test(t, p2.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: name, Arg: "true"})
+		}
+
+		// after Reset
+		p.Reset()
+		p2 = p
+		p.Feed([]string{"-c"})
+		p2.Feed([]string{"-z"})
+		gotwant.TestError(t, p.Parse(), nil)
+		gotwant.TestError(t, p2.Parse(), nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "c", Arg: "true"})
+		gotwant.Test(t, p2.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "z", Arg: "true"})
+	})
+
+	t.Run("Warnings", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("remove")
+		p.HintDeprecatedAlias("rm", "remove", "", "v2")
+		p.HintCommand("rm")
+		g := p.Grammar()
+
+		cc, ww, err := g.Parse([]string{"rm", "file"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, cc, []cliparser.Component{
+			{Type: cliparser.Command, Name: "remove"},
+			{Type: cliparser.Arg, Arg: "file"},
+		})
+		gotwant.Test(t, ww, []cliparser.Warning{{Old: "rm", New: "remove", RemovedIn: "v2"}})
+
+		_, ww, err = g.Parse([]string{"remove", "file"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, len(ww), 0)
+	})
+
+	t.Run("ParseInto", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("sub")
+		p.HintAlias("s", "sub")
+		p.HintCommand("s")
+		p.HintWithArg("b", []string{"sub"})
+		g := p.Grammar()
+
+		dst := make([]cliparser.Component, 0, 8)
+		cc, _, err := g.ParseInto(dst, []string{"-a", "s", "-b", "ccc", "ddd"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, &cc[0] == &dst[:1][0], true)
+		gotwant.Test(t, cc, []cliparser.Component{
+			{Type: cliparser.Option, Name: "a", Arg: "true"},
+			{Type: cliparser.Command, Name: "sub"},
+			{Type: cliparser.Option, Name: "b", Arg: "ccc"},
+			{Type: cliparser.Arg, Arg: "ddd"},
+		})
+
+		cc, _, err = g.ParseInto(cc, []string{"-xy"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, cc, []cliparser.Component{
+			{Type: cliparser.Option, Name: "x", Arg: "true"},
+			{Type: cliparser.Option, Name: "y", Arg: "true"},
+		})
+	})
+
+	t.Run("ZeroAlloc", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("sub")
+		p.HintCommand("subsub", []string{"sub"})
+		p.HintAlias("s", "sub")
+		p.HintCommand("s")
+		p.HintWithArg("b", []string{"sub"})
+		p.HintWithArg("d", []string{"sub", "subsub"})
+		p.HintLongName("long")
+		g := p.Grammar()
+
+		dst := make([]cliparser.Component, 0, 16)
+		for _, args := range [][]string{
+			{},
+			{"-a", "-xyz", "--long", "-long", "arg1", "arg2"},
+			{"-a", "sub", "-b", "ccc", "subsub", "-d", "eee", "fff"},
+			{"s", "-b=ccc", "subsub", "-d", "=", `"e=e"`, "--", "-f"},
+		} {
+			allocs := testing.AllocsPerRun(100, func() {
+				var err error
+				dst, _, err = g.ParseInto(dst, args)
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+			gotwant.Test(t, allocs, 0.0, gotwant.Desc(fmt.Sprint(args)))
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("sub")
+		p.HintCommand("subsub", []string{"sub"})
+		p.HintWithArg("b", []string{"sub"})
+		p.HintWithArg("d", []string{"sub", "subsub"})
+		g := p.Grammar()
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 16)
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					arg := fmt.Sprintf("%d-%d", i, j)
+					cc, _, err := g.Parse([]string{"-a", "sub", "-b", arg, "subsub", "-d=" + arg, arg})
+					if err != nil {
+						errs <- err
+						return
+					}
+					if len(cc) != 6 || cc[2].Arg != arg || cc[4].Arg != arg || cc[5].Arg != arg {
+						errs <- fmt.Errorf("unexpected result %v", cc)
+						return
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Error(err)
+		}
+	})
+}
+
+func BenchmarkParseInto(b *testing.B) {
+	p := cliparser.New()
+	p.HintCommand("sub")
+	p.HintCommand("subsub", []string{"sub"})
+	p.HintWithArg("b", []string{"sub"})
+	p.HintWithArg("d", []string{"sub", "subsub"})
+	g := p.Grammar()
+
+	args := []string{"-a", "sub", "-b", "ccc", "subsub", "-d", "eee", "fff"}
+	dst := make([]cliparser.Component, 0, 8)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst, _, _ = g.ParseInto(dst, args)
+	}
+}
@@ -0,0 +1,310 @@
+package cliparser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// HelpTemplate is the default template of Parser.WriteHelp, executed with HelpData.
+// It can use the functions
+//
+//	wrap indent text   // wraps text to the width, indenting each line
+//	hang indent text   // wraps text to the width, indenting each line but the first
+//	table entries      // lays out entries in two columns
+//
+// Widths are counted in runes.
+const HelpTemplate = `Usage: {{hang 7 .Usage}}
+{{- if .Description}}
+
+{{wrap 0 .Description}}
+{{- end}}
+{{- if .Commands}}
+
+Commands:
+{{table .Commands}}
+{{- end}}
+{{- if .Options}}
+
+Options:
+{{table .Options}}
+{{- end}}
+{{- if .InheritedOptions}}
+
+Inherited Options:
+{{table .InheritedOptions}}
+{{- end}}
+`
+
+// HelpOptions customizes Parser.WriteHelp.
+type HelpOptions struct {
+	// Width is the terminal width to wrap to. Zero means 80.
+	Width int
+	// Template overrides HelpTemplate.
+	Template string
+}
+
+// HelpData is given to the help template.
+type HelpData struct {
+	// Name is the program name followed by the commands of the namespace (e.g. "tool remote add").
+	Name string
+	// Usage is the usage line (e.g. "tool remote [options] <command> [args...]").
+	Usage       string
+	Description string
+
+	Commands []HelpEntry
+	Options  []HelpEntry
+	// InheritedOptions are options inherited from the ancestors.
+	InheritedOptions []HelpEntry
+
+	Width int
+}
+
+// HelpEntry is a row of a command list or an option table.
+type HelpEntry struct {
+	// Name is the names joined (e.g. "-t, --tag TAG" or "remove, rm").
+	Name        string
+	Description string
+}
+
+// WriteHelp writes help of the command of the namespace ns to w.
+// Deprecated aliases are not shown.
+func (p Parser) WriteHelp(w io.Writer, ns []string, opts HelpOptions) error {
+	data, err := p.helpData(ns)
+	if err != nil {
+		return err
+	}
+
+	data.Width = opts.Width
+	if data.Width <= 0 {
+		data.Width = 80
+	}
+
+	text := opts.Template
+	if text == "" {
+		text = HelpTemplate
+	}
+	tmpl, err := template.New("help").Funcs(template.FuncMap{
+		"wrap": func(indent int, text string) string {
+			return wrap(text, indent, data.Width)
+		},
+		"hang": func(indent int, text string) string {
+			return wrap(text, indent, data.Width)[indent:]
+		},
+		"table": func(entries []HelpEntry) string {
+			return table(entries, data.Width)
+		},
+	}).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
+
+func (p Parser) helpData(ns []string) (HelpData, error) {
+	spec := p.Spec()
+
+	c := spec.Lookup(ns)
+	if c == nil {
+		return HelpData{}, fmt.Errorf("no command %q", strings.Join(ns, " "))
+	}
+
+	name := spec.Name
+	if name == "" {
+		name = "PROG"
+	}
+	data := HelpData{
+		Name:        strings.Join(append([]string{name}, ns...), " "),
+		Description: c.Description,
+	}
+
+	own, inherited, builtin := spec.optionsOf(ns)
+	for _, o := range append(own, builtin...) {
+		data.Options = append(data.Options, optionEntry(o))
+	}
+	for _, o := range inherited {
+		data.InheritedOptions = append(data.InheritedOptions, optionEntry(o))
+	}
+
+	for _, sub := range spec.commandsOf(c) {
+		data.Commands = append(data.Commands, HelpEntry{
+			Name:        strings.Join(append([]string{sub.Name}, sub.Aliases...), ", "),
+			Description: sub.Description,
+		})
+	}
+
+	data.Usage = data.Name
+	if len(data.Options) > 0 || len(data.InheritedOptions) > 0 {
+		data.Usage += " [options]"
+	}
+	if c.Default != "" {
+		data.Usage += " [command]"
+	} else if len(c.Commands) > 0 {
+		data.Usage += " <command>"
+	}
+	data.Usage += " [args...]"
+
+	return data, nil
+}
+
+// optionsOf returns the options of the command of ns, the options inherited from its ancestors,
+// and the built-in options not overridden by them.
+func (s *Spec) optionsOf(ns []string) (own, inherited, builtin []OptionSpec) {
+	levels := make([][]OptionSpec, 0, len(ns)+1)
+	for i := len(ns); i >= 0; i-- {
+		levels = append(levels, s.Lookup(ns[:i]).Options)
+	}
+	return collectOptions(levels, s.Help, s.Version)
+}
+
+// built-in options and command (see Parser.HintHelp and Parser.HintVersion)
+var (
+	helpOption    = OptionSpec{Name: "help", Aliases: []string{"h"}, Description: "show help"}
+	versionOption = OptionSpec{Name: "version", Description: "show the version"}
+	helpCommand   = CommandSpec{Name: "help", Description: "show help of a command"}
+)
+
+// collectOptions returns the options of levels[0], the inherited options of levels[1:] not overridden,
+// and the built-in options not overridden.
+// levels are the options of a namespace and its ancestors, the nearest first.
+func collectOptions(levels [][]OptionSpec, help, version bool) (own, inherited, builtin []OptionSpec) {
+	declared := make(map[string]bool)
+	declare := func(o OptionSpec) {
+		declared[o.Name] = true
+		for _, alias := range o.Aliases {
+			declared[alias] = true
+		}
+		for _, d := range o.Deprecated {
+			declared[d.Name] = true
+		}
+	}
+
+	if len(levels) > 0 {
+		own = levels[0]
+		for _, o := range own {
+			declare(o)
+		}
+	}
+	for i := 1; i < len(levels); i++ {
+		for _, o := range levels[i] {
+			if !o.Inherited || declared[o.Name] {
+				continue
+			}
+			inherited = append(inherited, o)
+			declare(o)
+		}
+	}
+
+	if help && !declared["h"] && !declared["help"] {
+		builtin = append(builtin, helpOption)
+	}
+	if version && !declared["version"] {
+		builtin = append(builtin, versionOption)
+	}
+	return own, inherited, builtin
+}
+
+// commandsOf returns the subcommands of c, followed by the built-in help command if not overridden.
+func (s *Spec) commandsOf(c *CommandSpec) []CommandSpec {
+	return withHelpCommand(c.Commands, s.Help)
+}
+
+// withHelpCommand returns cmds followed by the built-in help command if help is true and not overridden.
+func withHelpCommand(cmds []CommandSpec, help bool) []CommandSpec {
+	if !help || len(cmds) == 0 {
+		return cmds
+	}
+	for _, c := range cmds {
+		if c.Name == "help" {
+			return cmds
+		}
+	}
+	return append(cmds[:len(cmds):len(cmds)], helpCommand)
+}
+
+func optionEntry(o OptionSpec) HelpEntry {
+	var short, long []string
+	for _, name := range append([]string{o.Name}, o.Aliases...) {
+		switch {
+		case len(name) == 1:
+			short = append(short, "-"+name)
+		case o.LongName:
+			long = append(long, "-"+name)
+		default:
+			long = append(long, "--"+name)
+		}
+	}
+
+	e := HelpEntry{
+		Name:        strings.Join(append(short, long...), ", "),
+		Description: o.Description,
+	}
+	if o.WithArg {
+		metavar := o.Metavar
+		if metavar == "" {
+			metavar = strings.ToUpper(o.Name)
+		}
+		e.Name += " " + metavar
+	}
+	return e
+}
+
+// maxNameColumn is the widest name column of a table.
+// Longer names put their descriptions on the next line.
+const maxNameColumn = 30
+
+// table lays out entries in two columns, wrapping descriptions to width.
+func table(entries []HelpEntry, width int) string {
+	col := 0
+	for _, e := range entries {
+		if n := utf8.RuneCountInString(e.Name); n > col && n <= maxNameColumn {
+			col = n
+		}
+	}
+	indent := 2 + col + 2
+
+	var lines []string
+	for _, e := range entries {
+		line := "  " + e.Name
+		if e.Description == "" {
+			lines = append(lines, line)
+			continue
+		}
+
+		desc := wrap(e.Description, indent, width)
+		if utf8.RuneCountInString(e.Name) > col {
+			lines = append(lines, line, desc)
+			continue
+		}
+		lines = append(lines, line+strings.Repeat(" ", indent-utf8.RuneCountInString(line))+desc[indent:])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap wraps text into lines of width at most, indenting each line.
+// A word longer than a line is not split.
+func wrap(text string, indent, width int) string {
+	prefix := strings.Repeat(" ", indent)
+
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		line, n := "", 0
+		for _, word := range strings.Fields(para) {
+			w := utf8.RuneCountInString(word)
+			if line != "" && indent+n+1+w > width {
+				lines = append(lines, prefix+line)
+				line, n = "", 0
+			}
+			if line != "" {
+				line += " "
+				n++
+			}
+			line += word
+			n += w
+		}
+		lines = append(lines, prefix+line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,166 @@
+package cliparser_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+func TestHelp(t *testing.T) {
+	t.Run("Root", func(t *testing.T) {
+		p := newHelpParser()
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, nil, cliparser.HelpOptions{})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), `Usage: tool [options] <command> [args...]
+
+tool manages remote repositories.
+
+Commands:
+  remote  manage remotes
+  status
+
+Options:
+  -C DIR         run as if started in DIR
+  -v, --verbose  print more
+`)
+	})
+
+	t.Run("Subcommand", func(t *testing.T) {
+		p := newHelpParser()
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, []string{"remote"}, cliparser.HelpOptions{})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), `Usage: tool remote [options] <command> [args...]
+
+manage remotes
+
+Commands:
+  add         add a remote named NAME for the repository at URL
+  remove, rm
+
+Inherited Options:
+  -C DIR         run as if started in DIR
+  -v, --verbose  print more
+`)
+	})
+
+	t.Run("Wrap", func(t *testing.T) {
+		p := newHelpParser()
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, []string{"remote", "add"}, cliparser.HelpOptions{Width: 48})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), `Usage: tool remote add [options] [args...]
+
+add a remote named NAME for the repository at
+URL
+
+Options:
+  -t, --track TRACK  track only BRANCH instead
+                     of all the branches of the
+                     remote repository
+  -mirror
+
+Inherited Options:
+  -C DIR         run as if started in DIR
+  -v, --verbose  print more
+`)
+	})
+
+	t.Run("WrapUsage", func(t *testing.T) {
+		p := newHelpParser()
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, []string{"remote", "add"}, cliparser.HelpOptions{Width: 30, Template: "Usage: {{hang 7 .Usage}}\n"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), "Usage: tool remote add\n       [options] [args...]\n")
+	})
+
+	t.Run("WrapRunes", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintWithArg("o")
+		p.HintMetavar("o", "DÄT")
+		p.HintDescription("o", "ééé ééé ééé ééé")
+		p.HintOption("verbose")
+		p.HintDescription("verbose", "ààà ààà ààà")
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, nil, cliparser.HelpOptions{Width: 24, Template: "{{table .Options}}\n"})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), `  -o DÄT     ééé ééé ééé
+             ééé
+  --verbose  ààà ààà ààà
+`)
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		p := newHelpParser()
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, []string{"remote"}, cliparser.HelpOptions{
+			Template: "{{.Name}}: {{.Description}}\n{{range .Commands}}* {{.Name}}\n{{end}}",
+		})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), "tool remote: manage remotes\n* add\n* remove, rm\n")
+
+		err = p.WriteHelp(&buf, []string{"remote"}, cliparser.HelpOptions{Template: "{{.Unknown"})
+		gotwant.TestError(t, err, "unclosed action")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		p := newHelpParser()
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, []string{"remote", "unknown"}, cliparser.HelpOptions{})
+		gotwant.TestError(t, err, `no command "remote unknown"`)
+	})
+
+	t.Run("Hints", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("build")
+		p.HintDescription("build", "build the project")
+		p.HintWithArg("o", []string{"build"})
+		p.HintMetavar("o", "FILE", []string{"build"})
+		p.HintDescription("o", "write to FILE", []string{"build"})
+		p.HintDescription("", "build things", []string{"build"})
+
+		spec := p.Spec()
+		gotwant.Test(t, spec.Commands[0].Description, "build things")
+		gotwant.Test(t, spec.Commands[0].Options, []cliparser.OptionSpec{{Name: "o", WithArg: true, Metavar: "FILE", Description: "write to FILE"}})
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, []string{"build"}, cliparser.HelpOptions{})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), "Usage: PROG build [options] [args...]\n\nbuild things\n\nOptions:\n  -o FILE  write to FILE\n")
+	})
+
+	t.Run("BuiltIn", func(t *testing.T) {
+		p := newHelpParser()
+		p.HintHelp()
+		p.HintVersion()
+
+		var buf bytes.Buffer
+		err := p.WriteHelp(&buf, nil, cliparser.HelpOptions{})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, buf.String(), `Usage: tool [options] <command> [args...]
+
+tool manages remote repositories.
+
+Commands:
+  remote  manage remotes
+  status
+  help    show help of a command
+
+Options:
+  -C DIR         run as if started in DIR
+  -v, --verbose  print more
+  -h, --help     show help
+  --version      show the version
+`)
+	})
+}
@@ -0,0 +1,95 @@
+package cliparser_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+// Fixtures shared by the tests of multiple features.
+
+func newRemoteParser() cliparser.Parser {
+	p := cliparser.New()
+	p.HintWithArg("C")
+	p.HintCommand("remote")
+	p.HintCommand("add", []string{"remote"})
+	p.HintCommand("remove", []string{"remote"})
+	p.HintAlias("rm", "remove", []string{"remote"})
+	p.HintCommand("rm", []string{"remote"})
+	p.HintWithArg("t", []string{"remote", "add"})
+	p.HintCommand("status")
+	return p
+}
+
+func newCompletionParser() cliparser.Parser {
+	p := newRemoteParser()
+	p.HintAlias("v", "verbose")
+	p.HintInherited("verbose")
+	p.HintDeprecatedAlias("delete", "remove", "", "", []string{"remote"})
+	p.HintCommand("delete", []string{"remote"})
+	p.HintLongName("mirror", []string{"remote", "add"})
+	p.HintHelp()
+	return p
+}
+
+func newHelpParser() cliparser.Parser {
+	return cliparser.NewFromSpec(cliparser.Spec{
+		CommandSpec: cliparser.CommandSpec{
+			Name:        "tool",
+			Description: "tool manages remote repositories.",
+			Options: []cliparser.OptionSpec{
+				{Name: "C", WithArg: true, Metavar: "DIR", Description: "run as if started in DIR", Inherited: true},
+				{Name: "verbose", Aliases: []string{"v"}, Description: "print more", Inherited: true},
+			},
+			Commands: []cliparser.CommandSpec{
+				{
+					Name:        "remote",
+					Description: "manage remotes",
+					Commands: []cliparser.CommandSpec{
+						{
+							Name:        "add",
+							Description: "add a remote named NAME for the repository at URL",
+							Options: []cliparser.OptionSpec{
+								{Name: "track", Aliases: []string{"t"}, WithArg: true, Description: "track only BRANCH instead of all the branches of the remote repository"},
+								{Name: "mirror", LongName: true},
+							},
+						},
+						{
+							Name:       "remove",
+							Aliases:    []string{"rm"},
+							Deprecated: []cliparser.DeprecatedAlias{{Name: "delete"}},
+						},
+					},
+				},
+				{Name: "status"},
+			},
+		},
+	})
+}
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// testGolden compares got with the golden file, or updates it with -update.
+func testGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotwant.Test(t, string(got), string(want), gotwant.Desc(golden))
+}
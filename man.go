@@ -0,0 +1,161 @@
+package cliparser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManOptions customizes man pages.
+type ManOptions struct {
+	// Section is the section of the pages. Empty means "1".
+	Section string
+	// Date, Source (e.g. "tool 1.2.0") and Manual (e.g. "Tool Manual") are put in the header and footer.
+	// They are left empty unless given, so that the pages are reproducible.
+	Date   string
+	Source string
+	Manual string
+}
+
+func (o ManOptions) section() string {
+	if o.Section == "" {
+		return "1"
+	}
+	return o.Section
+}
+
+// ManPageName returns the name of the man page of the command of ns, without the section (e.g. tool-remote-add).
+func (p Parser) ManPageName(ns []string) string {
+	name := p.ProgName()
+	if name == "" {
+		name = "PROG"
+	}
+	return strings.Join(append([]string{name}, ns...), "-")
+}
+
+// WriteManPage writes a roff man page of the command of the namespace ns to w.
+// Deprecated aliases are not shown.
+func (p Parser) WriteManPage(w io.Writer, ns []string, opts ManOptions) error {
+	data, err := p.helpData(ns)
+	if err != nil {
+		return err
+	}
+	spec := p.Spec()
+	c := spec.Lookup(ns)
+
+	var b strings.Builder
+	title := p.ManPageName(ns)
+
+	fmt.Fprintf(&b, ".TH %s %s %s %s %s\n",
+		roffQuote(strings.ToUpper(title)), roffQuote(opts.section()), roffQuote(opts.Date), roffQuote(opts.Source), roffQuote(opts.Manual))
+
+	b.WriteString(".SH NAME\n")
+	b.WriteString(roffEscape(title))
+	if summary := strings.SplitN(data.Description, "\n", 2)[0]; summary != "" {
+		b.WriteString(` \- ` + roffEscape(summary))
+	}
+	b.WriteString("\n")
+
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s\n", roffEscape(data.Name))
+	b.WriteString(roffEscape(strings.TrimSpace(data.Usage[len(data.Name):])) + "\n")
+
+	if data.Description != "" {
+		b.WriteString(".SH DESCRIPTION\n")
+		writeRoffText(&b, data.Description, ".PP")
+	}
+
+	writeRoffEntries(&b, "OPTIONS", data.Options)
+	writeRoffEntries(&b, "INHERITED OPTIONS", data.InheritedOptions)
+	writeRoffEntries(&b, "COMMANDS", data.Commands)
+
+	var seeAlso []string
+	if len(ns) > 0 {
+		seeAlso = append(seeAlso, p.ManPageName(ns[:len(ns)-1]))
+	}
+	for _, sub := range c.Commands {
+		seeAlso = append(seeAlso, p.ManPageName(append(ns[:len(ns):len(ns)], sub.Name)))
+	}
+	if len(seeAlso) > 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		for i, name := range seeAlso {
+			sep := ","
+			if i == len(seeAlso)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(&b, ".BR %s (%s)%s\n", roffEscape(name), opts.section(), sep)
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// GenerateManPages writes the man pages of all the commands into dir,
+// named like tool.1, tool-remote.1 and tool-remote-add.1.
+func (p Parser) GenerateManPages(dir string, opts ManOptions) error {
+	spec := p.Spec()
+
+	var nss [][]string
+	spec.walk(nil, func(ns []string, c *CommandSpec) {
+		nss = append(nss, ns)
+	})
+
+	for _, ns := range nss {
+		f, err := os.Create(filepath.Join(dir, p.ManPageName(ns)+"."+opts.section()))
+		if err != nil {
+			return err
+		}
+		err = p.WriteManPage(f, ns, opts)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeRoffEntries(b *strings.Builder, section string, entries []HelpEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, ".SH %s\n", section)
+	for _, e := range entries {
+		b.WriteString(".TP\n")
+		fmt.Fprintf(b, ".B %s\n", roffEscape(e.Name))
+		if e.Description != "" {
+			writeRoffText(b, e.Description, ".IP")
+		}
+	}
+}
+
+// writeRoffText writes paragraphs separated by empty lines, with the request sep between them.
+func writeRoffText(b *strings.Builder, text, sep string) {
+	for i, para := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if i > 0 {
+			b.WriteString(sep + "\n")
+		}
+		for _, line := range strings.Split(para, "\n") {
+			b.WriteString(roffEscape(line) + "\n")
+		}
+	}
+}
+
+// roffEscape escapes s for a line of roff text.
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// roffQuote makes s an argument of a roff request.
+func roffQuote(s string) string {
+	return `"` + strings.Replace(roffEscape(s), `"`, `\(dq`, -1) + `"`
+}
@@ -0,0 +1,63 @@
+package cliparser_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+func TestMan(t *testing.T) {
+	t.Run("WriteManPage", func(t *testing.T) {
+		p := newHelpParser()
+		p.HintHelp()
+
+		var buf bytes.Buffer
+		err := p.WriteManPage(&buf, []string{"remote", "add"}, cliparser.ManOptions{Date: "2026-10-18", Source: "tool 1.0", Manual: "Tool Manual"})
+		gotwant.TestError(t, err, nil)
+		testGolden(t, filepath.Join("testdata", "man", "tool-remote-add.1"), buf.Bytes())
+
+		err = p.WriteManPage(&buf, []string{"unknown"}, cliparser.ManOptions{})
+		gotwant.TestError(t, err, `no command "unknown"`)
+	})
+
+	t.Run("GenerateManPages", func(t *testing.T) {
+		p := newHelpParser()
+		p.HintHelp()
+		p.HintDescription("status", "show the status.\n\nIt lists remotes\n.and branches.")
+
+		dir, err := ioutil.TempDir("", "man")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		err = p.GenerateManPages(dir, cliparser.ManOptions{Section: "8"})
+		gotwant.TestError(t, err, nil)
+
+		files, err := ioutil.ReadDir(dir)
+		gotwant.TestError(t, err, nil)
+		var names []string
+		for _, fi := range files {
+			names = append(names, fi.Name())
+
+			got, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+			gotwant.TestError(t, err, nil)
+			testGolden(t, filepath.Join("testdata", "man8", fi.Name()), got)
+		}
+		gotwant.Test(t, names, []string{"tool-remote-add.8", "tool-remote-remove.8", "tool-remote.8", "tool-status.8", "tool.8"})
+
+		err = p.GenerateManPages(filepath.Join(dir, "none"), cliparser.ManOptions{})
+		gotwant.TestError(t, err, "no such file")
+	})
+
+	t.Run("ManPageName", func(t *testing.T) {
+		p := newHelpParser()
+		gotwant.Test(t, p.ManPageName(nil), "tool")
+		gotwant.Test(t, p.ManPageName([]string{"remote", "add"}), "tool-remote-add")
+	})
+}
@@ -0,0 +1,135 @@
+package cliparser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteMarkdown writes a Markdown document of the command of the namespace ns to w.
+//
+// The sections are Usage, Options, Inherited Options, Commands, Examples and See Also,
+// whose headings make stable anchors (e.g. #options).
+// Each option and command has an anchor of its physical name (e.g. #option-tag and #command-add).
+// Commands link to the documents written by GenerateMarkdown.
+// Deprecated aliases are not shown.
+func (p Parser) WriteMarkdown(w io.Writer, ns []string) error {
+	data, err := p.helpData(ns)
+	if err != nil {
+		return err
+	}
+	spec := p.Spec()
+	c := spec.Lookup(ns)
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n", data.Name)
+	if data.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(data.Description))
+	}
+
+	fmt.Fprintf(&b, "\n## Usage\n\n```\n%s\n```\n", data.Usage)
+
+	own, inherited, builtin := spec.optionsOf(ns)
+	writeMarkdownOptions(&b, "Options", append(own, builtin...))
+	writeMarkdownOptions(&b, "Inherited Options", inherited)
+
+	if subs := spec.commandsOf(c); len(subs) > 0 {
+		b.WriteString("\n## Commands\n\n")
+		b.WriteString("| Command | Aliases | Description |\n")
+		b.WriteString("| --- | --- | --- |\n")
+		for _, sub := range subs {
+			name := "`" + sub.Name + "`"
+			if c.Lookup([]string{sub.Name}) != nil {
+				name = fmt.Sprintf("[%s](%s.md)", name, p.ManPageName(append(ns[:len(ns):len(ns)], sub.Name)))
+			}
+			fmt.Fprintf(&b, "| <a id=\"command-%s\"></a>%s | %s | %s |\n",
+				sub.Name, name, markdownCodes(sub.Aliases), markdownCell(sub.Description))
+		}
+	}
+
+	if len(c.Examples) > 0 {
+		b.WriteString("\n## Examples\n")
+		for _, e := range c.Examples {
+			b.WriteString("\n")
+			if e.Description != "" {
+				fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(e.Description))
+			}
+			fmt.Fprintf(&b, "```\n%s\n```\n", e.Command)
+		}
+	}
+
+	if len(ns) > 0 {
+		parent := ns[:len(ns)-1]
+		fmt.Fprintf(&b, "\n## See Also\n\n- [%s](%s.md)\n",
+			data.Name[:strings.LastIndex(data.Name, " ")], p.ManPageName(parent))
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// GenerateMarkdown writes the Markdown documents of all the commands into dir,
+// named like tool.md, tool-remote.md and tool-remote-add.md.
+func (p Parser) GenerateMarkdown(dir string) error {
+	spec := p.Spec()
+
+	var nss [][]string
+	spec.walk(nil, func(ns []string, c *CommandSpec) {
+		nss = append(nss, ns)
+	})
+
+	for _, ns := range nss {
+		f, err := os.Create(filepath.Join(dir, p.ManPageName(ns)+".md"))
+		if err != nil {
+			return err
+		}
+		err = p.WriteMarkdown(f, ns)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMarkdownOptions(b *strings.Builder, heading string, opts []OptionSpec) {
+	if len(opts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "\n## %s\n\n", heading)
+	b.WriteString("| Option | Argument | Description |\n")
+	b.WriteString("| --- | --- | --- |\n")
+	for _, o := range opts {
+		arg := ""
+		if o.WithArg {
+			arg = o.Metavar
+			if arg == "" {
+				arg = strings.ToUpper(o.Name)
+			}
+			arg = "`" + arg + "` (required)"
+		}
+		fmt.Fprintf(b, "| <a id=\"option-%s\"></a>%s | %s | %s |\n",
+			o.Name, markdownCodes(optionWords(o, false)), arg, markdownCell(o.Description))
+	}
+}
+
+// markdownCodes joins words as code spans.
+func markdownCodes(words []string) string {
+	var codes []string
+	for _, w := range words {
+		codes = append(codes, "`"+w+"`")
+	}
+	return strings.Join(codes, ", ")
+}
+
+// markdownCell escapes s for a table cell.
+func markdownCell(s string) string {
+	s = strings.Replace(strings.TrimSpace(s), "|", `\|`, -1)
+	return strings.Replace(s, "\n", "<br>", -1)
+}
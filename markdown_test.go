@@ -0,0 +1,76 @@
+package cliparser_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+func TestMarkdown(t *testing.T) {
+	newParser := func() cliparser.Parser {
+		p := newHelpParser()
+		p.HintHelp()
+		p.HintExample("tool remote add origin https://example.com/repo.git", "Add a remote named origin.", []string{"remote", "add"})
+		p.HintExample("tool remote add -t main upstream https://example.com/up.git", "", []string{"remote", "add"})
+		p.HintDescription("status", "show the status | summary.\nIt lists remotes.")
+		return p
+	}
+
+	t.Run("WriteMarkdown", func(t *testing.T) {
+		p := newParser()
+
+		var buf bytes.Buffer
+		err := p.WriteMarkdown(&buf, []string{"remote", "add"})
+		gotwant.TestError(t, err, nil)
+		testGolden(t, filepath.Join("testdata", "markdown", "tool-remote-add.md"), buf.Bytes())
+
+		err = p.WriteMarkdown(&buf, []string{"unknown"})
+		gotwant.TestError(t, err, `no command "unknown"`)
+	})
+
+	t.Run("GenerateMarkdown", func(t *testing.T) {
+		p := newParser()
+
+		dir, err := ioutil.TempDir("", "markdown")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		err = p.GenerateMarkdown(dir)
+		gotwant.TestError(t, err, nil)
+
+		files, err := ioutil.ReadDir(dir)
+		gotwant.TestError(t, err, nil)
+		var names []string
+		for _, fi := range files {
+			names = append(names, fi.Name())
+
+			got, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+			gotwant.TestError(t, err, nil)
+			testGolden(t, filepath.Join("testdata", "markdown", fi.Name()), got)
+		}
+		gotwant.Test(t, names, []string{"tool-remote-add.md", "tool-remote-remove.md", "tool-remote.md", "tool-status.md", "tool.md"})
+
+		err = p.GenerateMarkdown(filepath.Join(dir, "none"))
+		gotwant.TestError(t, err, "no such file")
+	})
+
+	t.Run("Spec", func(t *testing.T) {
+		p := newParser()
+
+		spec := p.Spec()
+		gotwant.Test(t, spec.Lookup([]string{"remote", "add"}).Examples, []cliparser.ExampleSpec{
+			{Command: "tool remote add origin https://example.com/repo.git", Description: "Add a remote named origin."},
+			{Command: "tool remote add -t main upstream https://example.com/up.git"},
+		})
+
+		p2 := cliparser.NewFromSpec(spec)
+		gotwant.Test(t, p2.Spec(), spec)
+	})
+}
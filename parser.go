@@ -2,7 +2,10 @@
 package cliparser
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -20,12 +23,27 @@ const (
 	Arg
 )
 
+// ComponentSource represents where a component comes from.
+// Sources are in order of precedence.
+type ComponentSource int
+
+const (
+	// SourceArgs is the command line.
+	SourceArgs ComponentSource = iota
+	// SourceEnv is an environment variable (see HintEnv).
+	SourceEnv
+	// SourceConfig is a config (see SetConfig).
+	SourceConfig
+)
+
 // Component is a resultant type of this package.
 type Component struct {
 	Type ComponentType
 
 	Name string
 	Arg  string
+
+	Source ComponentSource
 }
 
 type hintType int
@@ -35,6 +53,14 @@ const (
 	commandHint
 	withArgHint
 	longNameHint
+	optionHint
+	inheritedHint
+	defaultCommandHint
+	deprecatedHint
+	descriptionHint
+	metavarHint
+	exampleHint
+	envHint
 )
 
 type hint struct {
@@ -42,6 +68,9 @@ type hint struct {
 
 	name      string
 	namespace []string
+
+	text      string // message of deprecatedHint, description, metavar, description of an example or variable of envHint
+	removedIn string // version of deprecatedHint
 }
 
 func (t ComponentType) String() string {
@@ -57,10 +86,44 @@ func (t ComponentType) String() string {
 	}
 }
 
+func (s ComponentSource) String() string {
+	switch s {
+	case SourceArgs:
+		return "Args"
+	case SourceEnv:
+		return "Env"
+	case SourceConfig:
+		return "Config"
+	default:
+		return "Unknown"
+	}
+}
+
 func (c Component) String() string {
 	return fmt.Sprintf("Component{Type:%v, Name:%v, Arg:%v}", c.Type, c.Name, c.Arg)
 }
 
+// Warning is a notice of parsing, which does not make it fail.
+type Warning struct {
+	Namespace []string
+
+	// Old is a deprecated alias given, and New is its physical name.
+	Old, New  string
+	Message   string
+	RemovedIn string
+}
+
+func (w Warning) String() string {
+	s := fmt.Sprintf("%q is deprecated, use %q instead", w.Old, w.New)
+	if w.RemovedIn != "" {
+		s += fmt.Sprintf(" (to be removed in %s)", w.RemovedIn)
+	}
+	if w.Message != "" {
+		s += ": " + w.Message
+	}
+	return s
+}
+
 // Parser contains parsing configurations and methods.
 type Parser struct {
 	args []string
@@ -69,15 +132,62 @@ type Parser struct {
 
 	currNS              []string
 	hints               []hint
+	hintsOwner          *Parser // the Parser that appends to hints in place; its copies reallocate first
 	optsMaybeGrouped    bool
 	doubleHyphenEnabled bool
+	help                bool
+	version             bool
+	envPrefix           string
+	lookupEnv           func(key string) (string, bool)
+	config              Config
+
+	grammar    *Grammar
+	resolver   func(ns []string, word string) (*CommandSpec, bool)
+	completers map[string]ValueCompleter
+
+	warnings []Warning
+
+	progName        string // by NewFromSpec
+	argv0           string // the program name given by FeedOS
+	progCmdPending  bool   // argv0 is not parsed yet
+	multiCall       bool
+	multiCallPrefix string
+}
+
+// state is per-call parsing state over a Grammar.
+type state struct {
+	g    *Grammar
+	node *namespace
+
+	p  *Parser               // to follow hints given by fn
+	fn func(Component) error // nil to append to result
+
+	args     []string
+	curr     string // the rest of the arg being tokenized
+	unescape bool   // args are not given via Feed
+	progCmd  string // the program name as an implicit command
+	warnings []Warning
+	result   []Component
+
+	// partial parsing leaves the trailing option pending, and ignores built-ins.
+	partial   bool
+	optName   string // the pending option name
+	eqGiven   bool
+	argsGiven bool // no more options nor commands
+
+	given []givenOption // options given so far, if the grammar has env bindings or config
+}
+
+// givenOption is an option given, by the namespace whose hints apply to it (see namespace.optionNS).
+type givenOption struct {
+	ns     []string
+	name   string
+	source ComponentSource
 }
 
 // New makes a Parser.
 func New() Parser {
 	return Parser{
-		result:              make([]Component, 0, 8),
-		hints:               make([]hint, 0, 16),
 		optsMaybeGrouped:    true,
 		doubleHyphenEnabled: true,
 	}
@@ -86,23 +196,57 @@ func New() Parser {
 // Reset resets its parsing results, except hints.
 // Next, call Feed and Parse.
 func (p *Parser) Reset() {
-	p.args = p.args[:0]
-	p.result = p.result[:0]
-	p.currNS = p.currNS[:0]
+	p.args = nil
+	p.result = nil
+	p.currNS = nil
+	p.progCmdPending = false
+	p.warnings = nil
 }
 
 // Feed is called when you pass os.Args.
 // On next step, call Parser.Parse.
 func (p *Parser) Feed(args []string) {
+	// copies of a Parser share the backing arrays of per-call buffers, so never append in place
+	p.args = p.args[:len(p.args):len(p.args)]
 	for _, arg := range args {
-		if strings.HasPrefix(arg, `\"`) {
-			arg = arg[1:]
-		}
-		if strings.HasSuffix(arg, `\"`) {
-			arg = arg[:len(arg)-2] + `"`
-		}
-		p.args = append(p.args, arg)
+		p.args = append(p.args, unescape(arg))
+	}
+}
+
+// FeedOS is Feed for os.Args, whose first element is the program.
+// The program name is recorded for HintMultiCall, and for ProgName unless Spec.Name is given.
+func (p *Parser) FeedOS(args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	name := filepath.Base(args[0])
+	if strings.HasSuffix(strings.ToLower(name), ".exe") {
+		name = name[:len(name)-len(".exe")]
 	}
+	p.argv0 = name
+	p.progCmdPending = true
+
+	p.Feed(args[1:])
+}
+
+// ProgName returns the program name: Spec.Name given by NewFromSpec or HintSpec,
+// or the one given by FeedOS, without its directory and .exe.
+func (p Parser) ProgName() string {
+	if p.progName != "" {
+		return p.progName
+	}
+	return p.argv0
+}
+
+func unescape(arg string) string {
+	if strings.HasPrefix(arg, `\"`) {
+		arg = arg[1:]
+	}
+	if strings.HasSuffix(arg, `\"`) {
+		arg = arg[:len(arg)-2] + `"`
+	}
+	return arg
 }
 
 // HintAlias is for defining another name.
@@ -115,7 +259,23 @@ func (p *Parser) HintAlias(alias, name string, optNS ...[]string) {
 	if len(optNS) > 0 {
 		h.namespace = optNS[0]
 	}
-	p.hints = append(p.hints, h)
+	p.addHint(h)
+}
+
+// HintDeprecatedAlias is HintAlias for a deprecated alias.
+// The alias still works, but its use is reported by Parser.Warnings.
+// message and removedIn (the version the alias will be removed in) may be empty.
+func (p *Parser) HintDeprecatedAlias(alias, name, message, removedIn string, optNS ...[]string) {
+	if alias == name {
+		return
+	}
+	p.HintAlias(alias, name, optNS...)
+
+	h := hint{typ: deprecatedHint, name: alias, text: message, removedIn: removedIn}
+	if len(optNS) > 0 {
+		h.namespace = optNS[0]
+	}
+	p.addHint(h)
 }
 
 // HintCommand is for giving the parser hint that the name is command.
@@ -124,7 +284,7 @@ func (p *Parser) HintCommand(name string, optNS ...[]string) {
 	if len(optNS) > 0 {
 		h.namespace = optNS[0]
 	}
-	p.hints = append(p.hints, h)
+	p.addHint(h)
 }
 
 // HintWithArg is for giving the parser hint that the name is option and it requires an argument.
@@ -133,7 +293,7 @@ func (p *Parser) HintWithArg(name string, optNS ...[]string) {
 	if len(optNS) > 0 {
 		h.namespace = optNS[0]
 	}
-	p.hints = append(p.hints, h)
+	p.addHint(h)
 }
 
 // HintLongName is for giving the parser hint that the name is option has a long name even if ONE-HYPHEND (-hoge)
@@ -142,111 +302,453 @@ func (p *Parser) HintLongName(name string, optNS ...[]string) {
 	if len(optNS) > 0 {
 		h.namespace = optNS[0]
 	}
+	p.addHint(h)
+}
+
+// HintOption declares the name as an option.
+// Options are parsed without this hint; it is for describing the grammar (see Spec).
+func (p *Parser) HintOption(name string, optNS ...[]string) {
+	h := hint{typ: optionHint, name: name}
+	if len(optNS) > 0 {
+		h.namespace = optNS[0]
+	}
+	p.addHint(h)
+}
+
+// Everywhere is a namespace meaning all namespaces.
+// Hints of options given with it are the same as given at the root and with HintInherited.
+var Everywhere = []string{"*"}
+
+func isEverywhere(ns []string) bool {
+	return len(ns) == 1 && ns[0] == Everywhere[0]
+}
+
+func (p *Parser) addHint(h hint) {
+	everywhere := isEverywhere(h.namespace)
+	if everywhere {
+		h.namespace = nil
+	}
+	// the caller may reuse the namespace
+	h.namespace = append([]string(nil), h.namespace...)
+
+	// copies of a Parser share the backing array of hints
+	if p.hintsOwner != p {
+		p.hints = append(make([]hint, 0, len(p.hints)+16), p.hints...)
+		p.hintsOwner = p
+	}
 	p.hints = append(p.hints, h)
+
+	if everywhere && h.typ != inheritedHint && h.typ != commandHint {
+		name := h.name
+		if h.typ == aliasHint {
+			// the option, not only the alias, is inherited (see namespace.inherit)
+			_, name = splitAlias(name)
+		}
+		p.hints = append(p.hints, hint{typ: inheritedHint, name: name})
+	}
+
+	p.grammar = nil
+}
+
+// HintInherited makes hints of the option name (and its aliases) in the namespace inherited by all descendant namespaces.
+// If name is an alias, the option it points to is inherited.
+// Hints of the same name in a descendant namespace override inherited ones.
+func (p *Parser) HintInherited(name string, optNS ...[]string) {
+	h := hint{typ: inheritedHint, name: name}
+	if len(optNS) > 0 {
+		h.namespace = optNS[0]
+	}
+	p.addHint(h)
+}
+
+// HintDefaultCommand is for giving the parser hint that the name is the default command of the namespace.
+// When an argument or the end is reached without a command in the namespace, the default command is given implicitly.
+func (p *Parser) HintDefaultCommand(name string, optNS ...[]string) {
+	p.HintCommand(name, optNS...)
+
+	h := hint{typ: defaultCommandHint, name: name}
+	if len(optNS) > 0 {
+		h.namespace = optNS[0]
+	}
+	p.addHint(h)
+}
+
+// HintDescription describes the option or command name for help.
+// An empty name describes the command of the namespace itself.
+func (p *Parser) HintDescription(name, description string, optNS ...[]string) {
+	h := hint{typ: descriptionHint, name: name, text: description}
+	if len(optNS) > 0 {
+		h.namespace = optNS[0]
+	}
+	p.addHint(h)
+}
+
+// HintMetavar names the argument of the option name for help (e.g. FILE of --config FILE).
+func (p *Parser) HintMetavar(name, metavar string, optNS ...[]string) {
+	h := hint{typ: metavarHint, name: name, text: metavar}
+	if len(optNS) > 0 {
+		h.namespace = optNS[0]
+	}
+	p.addHint(h)
+}
+
+// HintExample gives an example command line of the command of the namespace for help (e.g. "tool remote add origin URL").
+func (p *Parser) HintExample(command, description string, optNS ...[]string) {
+	h := hint{typ: exampleHint, name: command, text: description}
+	if len(optNS) > 0 {
+		h.namespace = optNS[0]
+	}
+	p.addHint(h)
+}
+
+// HintEnv binds the option name to the environment variable.
+// When the option is not given in the namespace (or in an ancestor namespace for an inherited option),
+// parsing emits it with the value of the variable, if set, before the next command or at the end.
+// Such components have the source SourceEnv.
+func (p *Parser) HintEnv(name, variable string, optNS ...[]string) {
+	h := hint{typ: envHint, name: name, text: variable}
+	if len(optNS) > 0 {
+		h.namespace = optNS[0]
+	}
+	p.addHint(h)
+}
+
+// HintEnvPrefix binds all the options not bound by HintEnv to environment variables
+// named by prefix, the namespace and the physical name in upper case, joined by _ (e.g. APP_REMOTE_ADD_FORCE).
+// An empty prefix disables it.
+func (p *Parser) HintEnvPrefix(prefix string) {
+	p.envPrefix = prefix
+	p.grammar = nil
+}
+
+// SetEnvLookup replaces os.LookupEnv for the environment variables of HintEnv and HintEnvPrefix.
+// nil restores os.LookupEnv.
+func (p *Parser) SetEnvLookup(lookup func(key string) (string, bool)) {
+	p.lookupEnv = lookup
+	p.grammar = nil
+}
+
+// HintMultiCall makes the program name given by FeedOS an implicit first command, like busybox.
+// prefix is trimmed from the program name, so that "tool-build" with prefix "tool-" behaves like "tool build".
+// The name is resolved by HintAlias, and is ignored if it is not a command.
+func (p *Parser) HintMultiCall(prefix string) {
+	p.multiCall = true
+	p.multiCallPrefix = prefix
+}
+
+// SetCommandResolver sets a hook that is called when a word in a namespace ns is not a known command.
+// If it returns a CommandSpec (and true), its hints are given in ns, and the word is tested again.
+// This is for loading grammars of subcommands on demand.
+func (p *Parser) SetCommandResolver(resolver func(ns []string, word string) (*CommandSpec, bool)) {
+	p.resolver = resolver
 }
 
 // HintNoOptionsGrouped disallows -abc -> -a -b -c
 func (p *Parser) HintNoOptionsGrouped() {
 	p.optsMaybeGrouped = false
+	p.grammar = nil
 }
 
 // HintDisableDoubleHyphen disallows -abc -> -a -b -c
 func (p *Parser) HintDisableDoubleHyphen() {
 	p.doubleHyphenEnabled = false
+	p.grammar = nil
 }
 
-func (p Parser) toPhysicalName(alias string) string {
-	for ai := 0; ai < len(p.hints); ai++ {
-		if p.hints[ai].typ != aliasHint {
-			continue
-		}
-		if strings.HasPrefix(p.hints[ai].name, alias+":") && len(p.currNS) == len(p.hints[ai].namespace) {
-			for i := 0; i < len(p.hints[ai].namespace); i++ {
-				if p.currNS[i] != p.hints[ai].namespace[i] {
-					return alias
-				}
-			}
-			return p.hints[ai].name[len(alias)+1:]
-		}
+// HintHelp enables the built-in -h, --help and "help <command>" in any namespace.
+// Parsing them returns *HelpRequestError.
+// Options and commands hinted with the same names take precedence.
+func (p *Parser) HintHelp() {
+	p.help = true
+	p.grammar = nil
+}
+
+// HintVersion enables the built-in --version in any namespace.
+// Parsing it returns ErrVersionRequested.
+// An option hinted with the same name takes precedence.
+func (p *Parser) HintVersion() {
+	p.version = true
+	p.grammar = nil
+}
+
+// GetComponent returns a Component. At end of source stream, this returns nil.
+func (p *Parser) GetComponent() *Component {
+	if len(p.result) == 0 {
+		return nil
 	}
-	return alias
+
+	c := &(p.result[0])
+	p.result = p.result[1:]
+
+	return c
 }
 
-func (p Parser) testCommand(name string) bool {
-	for ci := 0; ci < len(p.hints); ci++ {
-		if p.hints[ci].name == name && len(p.currNS) == len(p.hints[ci].namespace) {
-			if p.hints[ci].typ != commandHint {
-				continue
-			}
-			ok := true
-			for i := 0; i < len(p.hints[ci].namespace); i++ {
-				if p.currNS[i] != p.hints[ci].namespace[i] {
-					ok = false
-				}
-			}
-			if ok {
-				return true
-			}
+// ErrStop is returned by a callback of Parser.ParseFunc to stop parsing.
+var ErrStop = errors.New("stop parsing")
+
+// ErrHelpRequested is matched by *HelpRequestError with errors.Is.
+var ErrHelpRequested = errors.New("help requested")
+
+// ErrVersionRequested is returned by parsing --version (see HintVersion).
+var ErrVersionRequested = errors.New("version requested")
+
+// HelpRequestError is returned by parsing -h, --help or "help <command>" (see HintHelp).
+type HelpRequestError struct {
+	// Namespace is the command to help.
+	Namespace []string
+}
+
+func (e *HelpRequestError) Error() string {
+	if len(e.Namespace) == 0 {
+		return ErrHelpRequested.Error()
+	}
+	return fmt.Sprintf("%v for command %q", ErrHelpRequested, strings.Join(e.Namespace, " "))
+}
+
+// Is makes errors.Is(err, ErrHelpRequested) true.
+func (e *HelpRequestError) Is(target error) bool {
+	return target == ErrHelpRequested
+}
+
+// ParseFunc parses like Parse, but passes each component to fn as soon as it is recognized,
+// instead of storing it for GetComponent.
+//
+// fn may give hints to p; they take effect on the rest of the tokens.
+// If fn returns ErrStop (or an error wrapping it), ParseFunc stops and returns nil. Other errors are returned as they are.
+func (p *Parser) ParseFunc(fn func(c Component) error) error {
+	err := p.parse(fn)
+	if errors.Is(err, ErrStop) {
+		return nil
+	}
+	return err
+}
+
+// Warnings returns the warnings of the last parsing.
+func (p Parser) Warnings() []Warning {
+	return p.warnings
+}
+
+// Rest returns the args not parsed yet, e.g. after ParseFunc is stopped.
+func (p *Parser) Rest() []string {
+	return p.args
+}
+
+// Parse parses given (at Parser.Feed) command line string.
+// Call Parser.GetComponent-s serially to get results.
+func (p *Parser) Parse() error {
+	return p.parse(nil)
+}
+
+func (p *Parser) parse(fn func(Component) error) error {
+	g := p.Grammar()
+	s := state{
+		g:      g,
+		node:   g.lookup(p.currNS),
+		p:      p,
+		fn:     fn,
+		args:   p.args,
+		result: p.result[:len(p.result):len(p.result)], // never append in place, as in Feed
+	}
+	if p.multiCall && p.progCmdPending {
+		s.progCmd = strings.TrimPrefix(p.argv0, p.multiCallPrefix)
+	}
+	p.progCmdPending = false
+
+	s.warnings = nil
+
+	err := s.parse()
+	p.args, p.result, p.warnings = s.args, s.result, s.warnings
+	if s.node != nil {
+		p.currNS = s.node.path
+	}
+	if s.curr != "" {
+		p.args = append([]string{s.curr}, p.args...)
+	}
+	return err
+}
+
+// emit passes c to the callback, or appends it to the result.
+func (s *state) emit(c Component) error {
+	switch c.Type {
+	case Option:
+		if err := s.builtin(c.Name); err != nil {
+			return err
+		}
+		if s.g.defaults {
+			s.given = append(s.given, givenOption{ns: s.node.optionNS(c.Name).pathOrNil(), name: c.Name, source: c.Source})
+		}
+	case Command:
+		// the end of the namespace
+		if err := s.emitDefaults(); err != nil {
+			return err
 		}
 	}
-	return false
+
+	if s.fn == nil {
+		s.result = append(s.result, c)
+		return nil
+	}
+
+	if err := s.fn(c); err != nil {
+		return err
+	}
+	// the callback may have given hints
+	s.refresh()
+	return nil
 }
 
-func (p Parser) testWithArg(name string) bool {
-	for wi := 0; wi < len(p.hints); wi++ {
-		if p.hints[wi].typ != withArgHint {
+// emitDefaults emits the options of the namespace,
+// from environment variables unless given in the command line,
+// and then from the config unless given in the command line or by environment variables.
+// An inherited option given in an ancestor namespace is regarded as given.
+func (s *state) emitDefaults() error {
+	if !s.node.hasDefaults() {
+		return nil
+	}
+
+	lookup := s.g.lookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	for _, b := range s.node.env {
+		if s.isGiven(b.name, SourceEnv) {
 			continue
 		}
-		if p.hints[wi].name == name && len(p.currNS) == len(p.hints[wi].namespace) {
-			ok := true
-			for i := 0; i < len(p.hints[wi].namespace); i++ {
-				if p.currNS[i] != p.hints[wi].namespace[i] {
-					ok = false
-				}
-			}
-			if ok {
-				return true
-			}
+		value, found := lookup(b.variable)
+		if !found {
+			continue
+		}
+		if err := s.emit(Component{
+			Type:   Option,
+			Name:   b.name,
+			Arg:    value,
+			Source: SourceEnv,
+		}); err != nil {
+			return err
 		}
 	}
-	return false
-}
 
-func (p Parser) testLongName(name string) bool {
-	for li := 0; li < len(p.hints); li++ {
-		if p.hints[li].typ != longNameHint {
+	for _, v := range s.node.config {
+		if s.isGiven(v.Name, SourceConfig) {
 			continue
 		}
-		if p.hints[li].name == name && len(p.currNS) == len(p.hints[li].namespace) {
-			ok := true
-			for i := range p.hints[li].namespace {
-				if p.currNS[i] != p.hints[li].namespace[i] {
-					ok = false
-				}
-			}
-			if ok {
-				return true
-			}
+		if err := s.emit(Component{
+			Type:   Option,
+			Name:   v.Name,
+			Arg:    v.Value,
+			Source: SourceConfig,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isGiven reports whether the option name of the current namespace is given so far
+// from a source prior to src.
+func (s *state) isGiven(name string, src ComponentSource) bool {
+	ns := s.node.optionNS(name).pathOrNil()
+	for _, g := range s.given {
+		if g.source < src && g.name == name && equalNS(g.ns, ns) {
+			return true
 		}
 	}
 	return false
 }
 
-// GetComponent returns a Component. At end of source stream, this returns nil.
-func (p *Parser) GetComponent() *Component {
-	if len(p.result) == 0 {
+// builtin returns the error of the built-in option name, or nil if it is not built-in.
+func (s *state) builtin(name string) error {
+	if s.partial {
+		return nil
+	}
+	if n := s.node.optionNS(name); n != nil && n.declares(name) {
 		return nil
 	}
 
-	c := &(p.result[0])
-	p.result = p.result[1:]
+	switch {
+	case s.g.help && (name == "h" || name == "help"):
+		return &HelpRequestError{Namespace: append([]string(nil), s.path()...)}
+	case s.g.version && name == "version":
+		return ErrVersionRequested
+	}
+	return nil
+}
 
-	return c
+// helpCommand returns the error of the built-in help command,
+// taking the rest of the commands given as the namespace to help (e.g. help remote add).
+func (s *state) helpCommand() error {
+	node := s.node
+	for node != nil {
+		t, l := s.token()
+		if l == 0 || !node.testCommand(t) {
+			break
+		}
+		node = node.child(node.toPhysicalName(t))
+	}
+
+	e := &HelpRequestError{}
+	if node != nil {
+		e.Namespace = append([]string(nil), node.path...)
+	}
+	return e
 }
 
-// Parse parses given (at Parser.Feed) command line string.
-// Call Parser.GetComponent-s serially to get results.
-func (p *Parser) Parse() error {
+// physicalName is namespace.toPhysicalName warning about deprecated aliases.
+func (s *state) physicalName(alias string) string {
+	name := s.node.toPhysicalName(alias)
+	if d := s.node.deprecation(alias); d != nil {
+		s.warnings = append(s.warnings, Warning{
+			Namespace: append([]string(nil), s.path()...),
+			Old:       alias,
+			New:       name,
+			Message:   d.message,
+			RemovedIn: d.removedIn,
+		})
+	}
+	return name
+}
+
+// refresh follows hints given to the parser during parsing.
+func (s *state) refresh() {
+	if s.p == nil || s.p.grammar != nil {
+		return
+	}
+	s.g = s.p.Grammar()
+	s.node = s.g.lookup(s.path())
+}
+
+func (s *state) path() []string {
+	if s.node == nil {
+		return nil
+	}
+	return s.node.path
+}
+
+// testCommand is namespace.testCommand consulting the command resolver for unknown words.
+func (s *state) testCommand(word string) bool {
+	if s.node.testCommand(word) {
+		return true
+	}
+	if s.p == nil || s.p.resolver == nil {
+		return false
+	}
+
+	ns := append([]string(nil), s.path()...)
+	spec, ok := s.p.resolver(ns, word)
+	if !ok || spec == nil {
+		return false
+	}
+	s.p.hintSubcommand(ns, *spec)
+	s.refresh()
+	return s.node.testCommand(word)
+}
+
+// enter moves into the namespace of the command.
+func (s *state) enter(name string) {
+	s.node = s.node.child(name)
+}
+
+func (s *state) parse() error {
 	var optName string
 	var eqGiven bool
 	var argsGiven bool
@@ -254,24 +756,40 @@ func (p *Parser) Parse() error {
 	var doubleDash bool
 
 	// clear result
-	p.result = p.result[:0]
+	s.result = s.result[:0]
+
+	if s.progCmd != "" && s.testCommand(s.progCmd) {
+		name := s.physicalName(s.progCmd)
+		if err := s.emit(Component{
+			Type: Command,
+			Name: name,
+		}); err != nil {
+			return err
+		}
+		s.enter(name)
+	}
 
 	for {
-		t, l := token(&p.args)
+		t, l := s.token()
 		if l == 0 {
 			break
 		}
 
-		if p.doubleHyphenEnabled {
+		if s.g.doubleHyphenEnabled {
 			if doubleDash {
-				if optName != "" && !p.testWithArg(optName) {
+				if optName != "" && !s.node.testWithArg(optName) {
 					return fmt.Errorf("option %q without arguments", optName)
 				}
-				p.result = append(p.result, Component{
+				if err := s.enterDefaults(); err != nil {
+					return err
+				}
+				if err := s.emit(Component{
 					Type: Arg,
 					Name: "",
 					Arg:  t,
-				})
+				}); err != nil {
+					return err
+				}
 				continue
 			}
 
@@ -282,23 +800,27 @@ func (p *Parser) Parse() error {
 		}
 
 		if argsGiven {
-			p.result = append(p.result, Component{
+			if err := s.emit(Component{
 				Type: Arg,
 				Name: "",
 				Arg:  t,
-			})
+			}); err != nil {
+				return err
+			}
 			continue
 		}
 
 		// option?
-		if (optName == "" || !p.testWithArg(optName)) && strings.HasPrefix(t, "-") && t != "--" {
+		if (optName == "" || !s.node.testWithArg(optName)) && strings.HasPrefix(t, "-") && t != "--" {
 			// first, process the prev option (because curr token is not an arg)
 			if optName != "" {
-				p.result = append(p.result, Component{
+				if err := s.emit(Component{
 					Type: Option,
-					Name: p.toPhysicalName(optName),
+					Name: s.physicalName(optName),
 					Arg:  "true",
-				})
+				}); err != nil {
+					return err
+				}
 			}
 
 			// long name?
@@ -311,11 +833,11 @@ func (p *Parser) Parse() error {
 			// long name or short-named options ?
 			optName = t[1:]
 			eqGiven = false
-			if p.testLongName(optName) {
+			if s.node.testLongName(optName) {
 				continue
 			}
 
-			if p.optsMaybeGrouped {
+			if s.g.optsMaybeGrouped {
 				// short names (-abc -> -a -b -c)
 
 				names := optName
@@ -323,14 +845,16 @@ func (p *Parser) Parse() error {
 				eqGiven = false
 				for ni := 0; ni < len(names); ni++ {
 					if optName != "" {
-						if p.testWithArg(optName) {
+						if s.node.testWithArg(optName) {
 							return fmt.Errorf("option %q without arguments", optName)
 						}
-						p.result = append(p.result, Component{
+						if err := s.emit(Component{
 							Type: Option,
-							Name: p.toPhysicalName(optName),
+							Name: s.physicalName(optName),
 							Arg:  "true",
-						})
+						}); err != nil {
+							return err
+						}
 					}
 
 					optName = names[ni : ni+1]
@@ -344,31 +868,35 @@ func (p *Parser) Parse() error {
 
 			if optName == "" {
 				return fmt.Errorf("appeared = while no option given")
-			} else if !p.testWithArg(optName) {
+			} else if !s.node.testWithArg(optName) {
 				return fmt.Errorf("option %q must not have an argument", optName)
 			}
 			continue
 
 		} else {
 			if optName != "" {
-				if p.testWithArg(optName) {
-					if p.testCommand(t) {
+				if s.node.testWithArg(optName) {
+					if s.node.testCommand(t) {
 						if eqGiven {
 							// first, process the prev option (because curr token is not an arg)
-							p.result = append(p.result, Component{
+							if err := s.emit(Component{
 								Type: Option,
-								Name: p.toPhysicalName(optName),
+								Name: s.physicalName(optName),
 								Arg:  "",
-							})
+							}); err != nil {
+								return err
+							}
 							optName = ""
 							eqGiven = false
 
 							// command
-							p.result = append(p.result, Component{
+							if err := s.emit(Component{
 								Type: Command,
-								Name: p.toPhysicalName(t),
-							})
-							p.currNS = append(p.currNS, p.toPhysicalName(t))
+								Name: s.physicalName(t),
+							}); err != nil {
+								return err
+							}
+							s.enter(s.node.toPhysicalName(t))
 
 						} else {
 							return fmt.Errorf("option %q without arguments", optName)
@@ -376,11 +904,13 @@ func (p *Parser) Parse() error {
 					} else {
 
 						// argument for an option
-						p.result = append(p.result, Component{
+						if err := s.emit(Component{
 							Type: Option,
-							Name: p.toPhysicalName(optName),
+							Name: s.physicalName(optName),
 							Arg:  t,
-						})
+						}); err != nil {
+							return err
+						}
 						optName = ""
 						eqGiven = false
 					}
@@ -388,100 +918,162 @@ func (p *Parser) Parse() error {
 					continue
 
 				} else {
-					p.result = append(p.result, Component{
+					if err := s.emit(Component{
 						Type: Option,
-						Name: p.toPhysicalName(optName),
+						Name: s.physicalName(optName),
 						Arg:  "true",
-					})
+					}); err != nil {
+						return err
+					}
 					optName = ""
 					eqGiven = false
 				}
 			}
 
 			// command or args
-			if p.testCommand(t) {
-				p.result = append(p.result, Component{
+			isCommand := s.testCommand(t)
+			if !isCommand && s.g.help && t == "help" {
+				if s.partial {
+					// stay in the namespace to complete the commands to help
+					continue
+				}
+				return s.helpCommand()
+			}
+			for !isCommand && s.node.defaultCommand() != "" {
+				if err := s.enterDefault(); err != nil {
+					return err
+				}
+				isCommand = s.testCommand(t)
+			}
+			if isCommand {
+				if err := s.emit(Component{
 					Type: Command,
-					Name: p.toPhysicalName(t),
-				})
-				p.currNS = append(p.currNS, p.toPhysicalName(t))
+					Name: s.physicalName(t),
+				}); err != nil {
+					return err
+				}
+				s.enter(s.node.toPhysicalName(t))
 			} else {
-				p.result = append(p.result, Component{
+				if err := s.emit(Component{
 					Type: Arg,
 					Name: "",
 					Arg:  t,
-				})
+				}); err != nil {
+					return err
+				}
 				argsGiven = true
 			}
 		}
 	}
 
+	if s.partial {
+		s.optName, s.eqGiven, s.argsGiven = optName, eqGiven, argsGiven || doubleDash
+		return nil
+	}
+
 	if optName != "" {
-		if p.testWithArg(optName) {
+		if s.node.testWithArg(optName) {
 			if eqGiven {
-				p.result = append(p.result, Component{
+				if err := s.emit(Component{
 					Type: Option,
-					Name: p.toPhysicalName(optName),
+					Name: s.physicalName(optName),
 					Arg:  "",
-				})
+				}); err != nil {
+					return err
+				}
 			} else {
 				return fmt.Errorf("option %q without arguments", optName)
 			}
 		} else {
-			p.result = append(p.result, Component{
+			if err := s.emit(Component{
 				Type: Option,
-				Name: p.toPhysicalName(optName),
+				Name: s.physicalName(optName),
 				Arg:  "true",
-			})
+			}); err != nil {
+				return err
+			}
 			//optName = ""
 			//eqGiven = false
 		}
 	}
 
+	if err := s.enterDefaults(); err != nil {
+		return err
+	}
+	return s.emitDefaults()
+}
+
+// enterDefault enters the default command of the current namespace.
+func (s *state) enterDefault() error {
+	name := s.node.defaultCommand()
+	if err := s.emit(Component{
+		Type: Command,
+		Name: name,
+	}); err != nil {
+		return err
+	}
+	s.enter(name)
 	return nil
 }
 
-func token(args *[]string) (t string, length int) {
-	if len(*args) == 0 {
-		return "", 0
+// enterDefaults enters default commands as deep as possible, as no more commands are given.
+func (s *state) enterDefaults() error {
+	for s.node.defaultCommand() != "" {
+		if err := s.enterDefault(); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// token returns the next token, consuming the args.
+func (s *state) token() (t string, length int) {
+	if s.curr == "" {
+		if len(s.args) == 0 {
+			return "", 0
+		}
+		s.curr = s.args[0]
+		if s.unescape {
+			s.curr = unescape(s.curr)
+		}
+		s.args = s.args[1:]
+	}
+	return token(&s.curr)
+}
 
-	src := (*args)[0]
-	if len(src) == 0 {
+func token(src *string) (t string, length int) {
+	if len(*src) == 0 {
 		return "", 0
 	}
 
-	switch src[0] {
+	switch (*src)[0] {
 	case '=':
 		t, length = "=", 1
 
 	case '"':
-		for i := 1; i < len(src); i++ {
-			if src[i] == '"' {
-				t, length = src[1:i], i+1
+		for i := 1; i < len(*src); i++ {
+			if (*src)[i] == '"' {
+				t, length = (*src)[1:i], i+1
 				break
 			}
 		}
 		if length == 0 { // centinel
-			t, length = src[1:], len(src)
+			t, length = (*src)[1:], len(*src)
 		}
 
 	default:
-		for i := 0; i < len(src); i++ {
-			if src[i] == '=' {
-				t, length = src[:i], i //+ 1
+		for i := 0; i < len(*src); i++ {
+			if (*src)[i] == '=' {
+				t, length = (*src)[:i], i //+ 1
 				break
 			}
 		}
 		if length == 0 { // centinel
-			t, length = src, len(src)
+			t, length = *src, len(*src)
 		}
 	}
 
-	// consume curr token on args
-	(*args)[0] = (*args)[0][length:]
-	if len((*args)[0]) == 0 {
-		*args = (*args)[1:]
-	}
+	// consume curr token
+	*src = (*src)[length:]
 	return t, length
 }
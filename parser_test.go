@@ -1,6 +1,9 @@
 package cliparser_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/shu-go/cliparser"
@@ -535,6 +538,570 @@ func TestParser(t *testing.T) {
 		c = p.GetComponent()
 		gotwant.Test(t, c, &cliparser.Component{Type: cliparser.Arg, Name: "", Arg: "--opt2"})
 	})
+
+	t.Run("ParseFunc", func(t *testing.T) {
+		p := cliparser.New()
+		p.Feed([]string{"-a", "sub", "-b", "ccc", "subsub", "-d", "eee", "fff"})
+		p.HintCommand("sub")
+
+		var got []cliparser.Component
+		err := p.ParseFunc(func(c cliparser.Component) error {
+			got = append(got, c)
+
+			// load the grammar of a command lazily
+			if c.Type == cliparser.Command && c.Name == "sub" {
+				p.HintWithArg("b", []string{"sub"})
+				p.HintCommand("subsub", []string{"sub"})
+			} else if c.Type == cliparser.Command && c.Name == "subsub" {
+				p.HintWithArg("d", []string{"sub", "subsub"})
+			}
+			return nil
+		})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, got, []cliparser.Component{
+			{Type: cliparser.Option, Name: "a", Arg: "true"},
+			{Type: cliparser.Command, Name: "sub"},
+			{Type: cliparser.Option, Name: "b", Arg: "ccc"},
+			{Type: cliparser.Command, Name: "subsub"},
+			{Type: cliparser.Option, Name: "d", Arg: "eee"},
+			{Type: cliparser.Arg, Arg: "fff"},
+		})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		p.Reset()
+		p.Feed([]string{"-a", "sub", "-b", "ccc"})
+		got = got[:0]
+		err = p.ParseFunc(func(c cliparser.Component) error {
+			got = append(got, c)
+			if c.Type == cliparser.Command {
+				return cliparser.ErrStop
+			}
+			return nil
+		})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, got, []cliparser.Component{
+			{Type: cliparser.Option, Name: "a", Arg: "true"},
+			{Type: cliparser.Command, Name: "sub"},
+		})
+		gotwant.Test(t, p.Rest(), []string{"-b", "ccc"})
+
+		// wrapped
+		p.Reset()
+		p.Feed([]string{"-a", "sub", "-b", "ccc"})
+		err = p.ParseFunc(func(c cliparser.Component) error {
+			if c.Type == cliparser.Command {
+				return fmt.Errorf("done at %v: %w", c.Name, cliparser.ErrStop)
+			}
+			return nil
+		})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.Rest(), []string{"-b", "ccc"})
+
+		p.Reset()
+		p.Feed([]string{"-a", "-b"})
+		err = p.ParseFunc(func(c cliparser.Component) error {
+			return fmt.Errorf("unexpected %v", c.Name)
+		})
+		gotwant.TestError(t, err, "unexpected a")
+	})
+
+	t.Run("CommandResolver", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("builtin")
+
+		var resolved [][]string
+		p.SetCommandResolver(func(ns []string, word string) (*cliparser.CommandSpec, bool) {
+			resolved = append(resolved, append(ns, word))
+			switch {
+			case len(ns) == 0 && word == "plugin":
+				return &cliparser.CommandSpec{
+					Name:     "plugin",
+					Options:  []cliparser.OptionSpec{{Name: "b", WithArg: true}},
+					Commands: []cliparser.CommandSpec{{Name: "remove", Aliases: []string{"rm"}}},
+				}, true
+			case len(ns) == 1 && word == "sub":
+				return &cliparser.CommandSpec{Name: "sub"}, true
+			}
+			return nil, false
+		})
+
+		p.Feed([]string{"builtin", "sub", "-a"})
+		err := p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "builtin"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "sub"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "a", Arg: "true"})
+
+		p.Reset()
+		p.Feed([]string{"plugin", "-b", "ccc", "rm", "ddd"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "plugin"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "b", Arg: "ccc"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remove"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "ddd"})
+
+		// resolved once, then known
+		p.Reset()
+		p.Feed([]string{"plugin", "unknown"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "plugin"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "unknown"})
+
+		gotwant.Test(t, resolved, [][]string{
+			{"builtin", "sub"},
+			{"plugin"},
+			{"plugin", "remove", "ddd"},
+			{"plugin", "unknown"},
+		})
+	})
+
+	t.Run("FeedOS", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("build")
+		p.FeedOS([]string{"/usr/local/bin/tool", "build", "-a"})
+		gotwant.Test(t, p.ProgName(), "tool")
+
+		err := p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "build"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "a", Arg: "true"})
+
+		p.Reset()
+		p.FeedOS([]string{"bin/tool.EXE"})
+		gotwant.Test(t, p.ProgName(), "tool")
+	})
+
+	t.Run("MultiCall", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("build")
+		p.HintWithArg("o", []string{"build"})
+		p.HintAlias("mk", "build")
+		p.HintCommand("mk")
+		p.HintMultiCall("tool-")
+
+		p.FeedOS([]string{"/usr/bin/tool-build", "-o", "out", "src"})
+		err := p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "build"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "o", Arg: "out"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "src"})
+
+		// alias without prefix
+		p.Reset()
+		p.FeedOS([]string{"mk", "-o", "out"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "build"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "o", Arg: "out"})
+
+		// not a command
+		p.Reset()
+		p.FeedOS([]string{"tool", "build"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "build"})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		// Feed has no program name
+		p.Reset()
+		p.Feed([]string{"-o"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "o", Arg: "true"})
+
+		// the name of the spec is kept
+		p = cliparser.NewFromSpec(cliparser.Spec{CommandSpec: cliparser.CommandSpec{Name: "tool", Commands: []cliparser.CommandSpec{{Name: "build"}}}})
+		p.HintMultiCall("tool-")
+		p.FeedOS([]string{"/usr/bin/tool-build", "src"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "build"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "src"})
+		gotwant.Test(t, p.ProgName(), "tool")
+		gotwant.Test(t, p.ManPageName(nil), "tool")
+		var buf bytes.Buffer
+		gotwant.TestError(t, p.WriteHelp(&buf, []string{"build"}, cliparser.HelpOptions{}), nil)
+		gotwant.Test(t, buf.String(), "Usage: tool build [args...]\n")
+	})
+
+	t.Run("Inherited", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("remote")
+		p.HintCommand("add", []string{"remote"})
+		p.HintCommand("local")
+		p.HintWithArg("config")
+		p.HintAlias("c", "config")
+		p.HintWithArg("c")
+		p.HintInherited("config")
+		p.HintWithArg("t", []string{"remote"})
+		p.HintInherited("t", []string{"remote"})
+		p.HintWithArg("n", cliparser.Everywhere)
+		p.HintAlias("v", "verbose", cliparser.Everywhere)
+		p.HintOption("config", []string{"local"}) // override
+
+		p.Feed([]string{"remote", "add", "-c", "file", "-t", "main", "-n", "1", "-v", "origin"})
+		err := p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "add"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "config", Arg: "file"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "t", Arg: "main"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "n", Arg: "1"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "verbose", Arg: "true"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "origin"})
+
+		p.Reset()
+		p.Feed([]string{"local", "--config", "-t", "x", "-n", "2"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "local"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "config", Arg: "true"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "t", Arg: "true"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "x"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "-n"})
+
+		spec := p.Spec()
+		gotwant.Test(t, spec.Options, []cliparser.OptionSpec{
+			{Name: "config", Aliases: []string{"c"}, WithArg: true, Inherited: true},
+			{Name: "n", WithArg: true, Inherited: true},
+			{Name: "verbose", Aliases: []string{"v"}, Inherited: true},
+		})
+		gotwant.Test(t, spec.Lookup([]string{"remote"}).Options, []cliparser.OptionSpec{
+			{Name: "t", WithArg: true, Inherited: true},
+		})
+		gotwant.TestError(t, p.Validate(), nil)
+
+		p2 := cliparser.NewFromSpec(spec)
+		p2.Feed([]string{"remote", "add", "-c", "file"})
+		err = p2.Parse()
+		gotwant.TestError(t, err, nil)
+		p2.GetComponent()
+		p2.GetComponent()
+		gotwant.Test(t, p2.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "config", Arg: "file"})
+	})
+
+	t.Run("InheritedAlias", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("sub")
+		p.HintWithArg("v")
+		p.HintWithArg("verbose")
+		p.HintAlias("v", "verbose", cliparser.Everywhere)
+		p.HintWithArg("q")
+		p.HintAlias("quiet", "q")
+		p.HintInherited("quiet") // resolved to q
+
+		for i, p := range []cliparser.Parser{p, cliparser.NewFromSpec(p.Spec())} {
+			p.Feed([]string{"sub", "--verbose", "x", "-v", "y", "-q", "z"})
+			err := p.Parse()
+			gotwant.TestError(t, err, nil, gotwant.Desc(fmt.Sprint(i)))
+			gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "sub"})
+			gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "verbose", Arg: "x"}, gotwant.Desc(fmt.Sprint(i)))
+			gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "verbose", Arg: "y"}, gotwant.Desc(fmt.Sprint(i)))
+			gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "q", Arg: "z"}, gotwant.Desc(fmt.Sprint(i)))
+			gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+		}
+	})
+
+	t.Run("DefaultCommand", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintDefaultCommand("run")
+		p.HintCommand("build")
+		p.HintWithArg("n", []string{"run"})
+		p.HintDefaultCommand("all", []string{"build"})
+
+		cases := []struct {
+			args []string
+			want []cliparser.Component
+		}{
+			{
+				args: nil,
+				want: []cliparser.Component{{Type: cliparser.Command, Name: "run"}},
+			},
+			{
+				args: []string{"-v", "file"},
+				want: []cliparser.Component{
+					{Type: cliparser.Option, Name: "v", Arg: "true"},
+					{Type: cliparser.Command, Name: "run"},
+					{Type: cliparser.Arg, Arg: "file"},
+				},
+			},
+			{
+				args: []string{"run", "-n", "1"},
+				want: []cliparser.Component{
+					{Type: cliparser.Command, Name: "run"},
+					{Type: cliparser.Option, Name: "n", Arg: "1"},
+				},
+			},
+			{
+				args: []string{"build"},
+				want: []cliparser.Component{
+					{Type: cliparser.Command, Name: "build"},
+					{Type: cliparser.Command, Name: "all"},
+				},
+			},
+			{
+				args: []string{"--", "-n"},
+				want: []cliparser.Component{
+					{Type: cliparser.Command, Name: "run"},
+					{Type: cliparser.Arg, Arg: "-n"},
+				},
+			},
+		}
+		for _, c := range cases {
+			p.Reset()
+			p.Feed(c.args)
+			err := p.Parse()
+			gotwant.TestError(t, err, nil)
+
+			var got []cliparser.Component
+			for comp := p.GetComponent(); comp != nil; comp = p.GetComponent() {
+				got = append(got, *comp)
+			}
+			gotwant.Test(t, got, c.want, gotwant.Desc(fmt.Sprint(c.args)))
+		}
+
+		spec := p.Spec()
+		gotwant.Test(t, spec.Default, "run")
+		gotwant.Test(t, spec.Lookup([]string{"build"}).Default, "all")
+	})
+
+	t.Run("DeprecatedAlias", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("remove")
+		p.HintDeprecatedAlias("rm", "remove", "", "v2")
+		p.HintCommand("rm")
+		p.HintWithArg("force-level", []string{"remove"})
+		p.HintDeprecatedAlias("f", "force-level", "single letters are confusing", "", []string{"remove"})
+		p.HintWithArg("f", []string{"remove"})
+
+		p.Feed([]string{"rm", "-f", "2", "file"})
+		err := p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remove"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "force-level", Arg: "2"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "file"})
+
+		ww := p.Warnings()
+		gotwant.Test(t, ww, []cliparser.Warning{
+			{Old: "rm", New: "remove", RemovedIn: "v2"},
+			{Namespace: []string{"remove"}, Old: "f", New: "force-level", Message: "single letters are confusing"},
+		})
+		gotwant.Test(t, ww[0].String(), `"rm" is deprecated, use "remove" instead (to be removed in v2)`)
+		gotwant.Test(t, ww[1].String(), `"f" is deprecated, use "force-level" instead: single letters are confusing`)
+
+		p.Reset()
+		gotwant.Test(t, len(p.Warnings()), 0)
+		p.Feed([]string{"remove", "--force-level", "2"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, len(p.Warnings()), 0)
+
+		spec := p.Spec()
+		gotwant.Test(t, spec.Commands[0].Aliases, ([]string)(nil))
+		gotwant.Test(t, spec.Commands[0].Deprecated, []cliparser.DeprecatedAlias{{Name: "rm", RemovedIn: "v2"}})
+		gotwant.Test(t, spec.Commands[0].Options, []cliparser.OptionSpec{
+			{Name: "force-level", WithArg: true, Deprecated: []cliparser.DeprecatedAlias{{Name: "f", Message: "single letters are confusing"}}},
+		})
+
+		p2 := cliparser.NewFromSpec(spec)
+		gotwant.Test(t, p2.Spec(), spec)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("remote")
+		p.HintAlias("r", "remote")
+		p.HintCommand("r")
+		p.HintCommand("add", []string{"remote"})
+		p.HintWithArg("C")
+
+		p.Feed([]string{"-h"})
+		err := p.Parse()
+		gotwant.TestError(t, err, nil) // not enabled
+
+		p.HintHelp()
+
+		for _, c := range []struct {
+			args []string
+			ns   []string
+		}{
+			{args: []string{"-h"}},
+			{args: []string{"--help", "remote"}},
+			{args: []string{"-C", "dir", "remote", "add", "-h"}, ns: []string{"remote", "add"}},
+			{args: []string{"r", "--help"}, ns: []string{"remote"}},
+			{args: []string{"help"}},
+			{args: []string{"help", "r", "add", "more"}, ns: []string{"remote", "add"}},
+			{args: []string{"remote", "help", "add"}, ns: []string{"remote", "add"}},
+			{args: []string{"help", "unknown"}},
+		} {
+			p.Reset()
+			p.Feed(c.args)
+			err = p.Parse()
+			gotwant.Test(t, errors.Is(err, cliparser.ErrHelpRequested), true, gotwant.Desc(fmt.Sprint(c.args)))
+			gotwant.Test(t, err, &cliparser.HelpRequestError{Namespace: c.ns}, gotwant.Desc(fmt.Sprint(c.args)))
+		}
+		gotwant.TestError(t, &cliparser.HelpRequestError{}, "help requested")
+		gotwant.TestError(t, &cliparser.HelpRequestError{Namespace: []string{"remote", "add"}}, `help requested for command "remote add"`)
+
+		// not built-in
+		for _, args := range [][]string{
+			{"-C", "-h"},
+			{"arg", "-h"},
+			{"--", "-h"},
+			{"arg", "help"},
+		} {
+			p.Reset()
+			p.Feed(args)
+			err = p.Parse()
+			gotwant.TestError(t, err, nil, gotwant.Desc(fmt.Sprint(args)))
+		}
+
+		// hints take precedence
+		p.HintWithArg("h", []string{"remote"})
+		p.HintCommand("help", []string{"remote"})
+		p.Reset()
+		p.Feed([]string{"remote", "-h", "host", "help"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "h", Arg: "host"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "help"})
+
+		_, _, err = p.Grammar().Parse([]string{"remote", "add", "--help"})
+		gotwant.Test(t, err, &cliparser.HelpRequestError{Namespace: []string{"remote", "add"}})
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("remote")
+		p.HintVersion()
+
+		p.Feed([]string{"--version"})
+		err := p.Parse()
+		gotwant.Test(t, err, cliparser.ErrVersionRequested)
+
+		p.Reset()
+		p.Feed([]string{"remote", "--version"})
+		err = p.Parse()
+		gotwant.Test(t, err, cliparser.ErrVersionRequested)
+
+		p.Reset()
+		p.Feed([]string{"-h"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+
+		p.HintWithArg("version", []string{"remote"})
+		p.Reset()
+		p.Feed([]string{"remote", "--version", "1"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+
+		spec := p.Spec()
+		gotwant.Test(t, spec.Version, true)
+		gotwant.Test(t, spec.Help, false)
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		env := map[string]string{
+			"APP_TOKEN":            "secret",
+			"APP_REMOTE_ADD_T":     "main",
+			"APP_REMOTE_ADD_FORCE": "true",
+			"APP_V":                "true",
+			"APP_STATUS_SHORT":     "1",
+		}
+		lookup := func(key string) (string, bool) {
+			v, found := env[key]
+			return v, found
+		}
+
+		p := newRemoteParser()
+		p.HintWithArg("token")
+		p.HintAlias("tk", "token")
+		p.HintEnv("tk", "APP_TOKEN")
+		p.HintOption("force", []string{"remote", "add"})
+		p.SetEnvLookup(lookup)
+
+		p.Feed([]string{"remote", "add", "origin"})
+		err := p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "token", Arg: "secret", Source: cliparser.SourceEnv})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "add"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "origin"})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		// the command line wins
+		p.Reset()
+		p.Feed([]string{"--token", "given", "remote"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "token", Arg: "given"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		// prefix
+		p.HintEnvPrefix("APP")
+		p.Reset()
+		p.Feed([]string{"-C", "dir", "remote", "add", "-t", "dev"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "C", Arg: "dir"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "token", Arg: "secret", Source: cliparser.SourceEnv})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "add"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "t", Arg: "dev"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "force", Arg: "true", Source: cliparser.SourceEnv})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		// with a callback
+		var comps []cliparser.Component
+		p.Reset()
+		p.Feed([]string{"remote", "add"})
+		err = p.ParseFunc(func(c cliparser.Component) error {
+			comps = append(comps, c)
+			if c.Source == cliparser.SourceEnv && c.Name == "t" {
+				return cliparser.ErrStop
+			}
+			return nil
+		})
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, len(comps), 5)
+		gotwant.Test(t, comps[4], cliparser.Component{Type: cliparser.Option, Name: "t", Arg: "main", Source: cliparser.SourceEnv})
+		gotwant.Test(t, comps[4].Source.String(), "Env")
+
+		spec := p.Spec()
+		gotwant.Test(t, spec.EnvPrefix, "APP")
+		gotwant.Test(t, spec.Options[1], cliparser.OptionSpec{Name: "token", Aliases: []string{"tk"}, WithArg: true, Env: "APP_TOKEN"})
+		p2 := cliparser.NewFromSpec(spec)
+		gotwant.Test(t, p2.Spec(), spec)
+	})
+
+	t.Run("EnvInherited", func(t *testing.T) {
+		p := newRemoteParser()
+		p.HintInherited("C")
+		p.HintEnv("C", "APP_C", []string{"remote"})
+		p.SetEnvLookup(func(key string) (string, bool) {
+			return "env", key == "APP_C"
+		})
+
+		p.Feed([]string{"remote"})
+		err := p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "C", Arg: "env", Source: cliparser.SourceEnv})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+
+		// given in the parent namespace
+		p.Reset()
+		p.Feed([]string{"-C", "x", "remote"})
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Option, Name: "C", Arg: "x"})
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Command, Name: "remote"})
+		gotwant.Test(t, p.GetComponent(), (*cliparser.Component)(nil))
+	})
 }
 
 func BenchmarkParse(b *testing.B) {
@@ -550,3 +1117,38 @@ func BenchmarkParse(b *testing.B) {
 		p.Parse()
 	}
 }
+
+func BenchmarkParseLargeGrammar(b *testing.B) {
+	for _, n := range []int{10, 100, 1000, 10000} {
+		p := cliparser.New()
+		for i := 0; i < n; i++ {
+			p.HintCommand(fmt.Sprintf("sub%d", i))
+			p.HintCommand(fmt.Sprintf("subsub%d", i), []string{"sub0"})
+			p.HintWithArg(fmt.Sprintf("b%d", i), []string{"sub0"})
+			p.HintLongName(fmt.Sprintf("b%d", i), []string{"sub0"})
+			p.HintWithArg(fmt.Sprintf("d%d", i), []string{"sub0", fmt.Sprintf("subsub%d", n-1)})
+			p.HintLongName(fmt.Sprintf("d%d", i), []string{"sub0", fmt.Sprintf("subsub%d", n-1)})
+			p.HintAlias(fmt.Sprintf("s%d", i), fmt.Sprintf("sub%d", i))
+		}
+		args := []string{"-a", "sub0", "-b0", "ccc", fmt.Sprintf("subsub%d", n-1), fmt.Sprintf("-d%d", n-1), "eee", "fff"}
+		g := p.Grammar()
+
+		cc, _, err := g.Parse(args)
+		gotwant.TestError(b, err, nil)
+		gotwant.Test(b, cc, []cliparser.Component{
+			{Type: cliparser.Option, Name: "a", Arg: "true"},
+			{Type: cliparser.Command, Name: "sub0"},
+			{Type: cliparser.Option, Name: "b0", Arg: "ccc"},
+			{Type: cliparser.Command, Name: fmt.Sprintf("subsub%d", n-1)},
+			{Type: cliparser.Option, Name: fmt.Sprintf("d%d", n-1), Arg: "eee"},
+			{Type: cliparser.Arg, Arg: "fff"},
+		})
+
+		b.Run(fmt.Sprintf("Hints%d", n), func(b *testing.B) {
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.Parse(args)
+			}
+		})
+	}
+}
@@ -0,0 +1,487 @@
+package cliparser
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Spec is a declarative form of hints.
+// It can be built in Go, or read from and written to JSON.
+type Spec struct {
+	// CommandSpec is the root. Its Name is the program name (see Parser.ProgName).
+	CommandSpec
+
+	NoOptionsGrouped    bool `json:"noOptionsGrouped,omitempty"`
+	DisableDoubleHyphen bool `json:"disableDoubleHyphen,omitempty"`
+
+	// Help enables the built-in help (see Parser.HintHelp).
+	Help bool `json:"help,omitempty"`
+	// Version enables the built-in --version (see Parser.HintVersion).
+	Version bool `json:"version,omitempty"`
+	// EnvPrefix binds options to environment variables (see Parser.HintEnvPrefix).
+	EnvPrefix string `json:"envPrefix,omitempty"`
+}
+
+// CommandSpec describes a command, its options and its subcommands.
+type CommandSpec struct {
+	Name        string        `json:"name,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Aliases     []string      `json:"aliases,omitempty"`
+	Options     []OptionSpec  `json:"options,omitempty"`
+	Commands    []CommandSpec `json:"commands,omitempty"`
+
+	// Deprecated are aliases that still work but are reported by Parser.Warnings.
+	Deprecated []DeprecatedAlias `json:"deprecated,omitempty"`
+	// Default is the name of the default subcommand.
+	Default string `json:"default,omitempty"`
+	// Examples are for documents.
+	Examples []ExampleSpec `json:"examples,omitempty"`
+}
+
+// OptionSpec describes an option.
+type OptionSpec struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+	WithArg     bool     `json:"withArg,omitempty"`
+	// Metavar names the argument in help.
+	Metavar  string `json:"metavar,omitempty"`
+	LongName bool   `json:"longName,omitempty"`
+
+	// Deprecated are aliases that still work but are reported by Parser.Warnings.
+	Deprecated []DeprecatedAlias `json:"deprecated,omitempty"`
+	// Inherited makes the option available in all descendant namespaces.
+	Inherited bool `json:"inherited,omitempty"`
+	// Env is the environment variable bound to the option (see Parser.HintEnv).
+	Env string `json:"env,omitempty"`
+}
+
+// DeprecatedAlias describes a deprecated alias (see Parser.HintDeprecatedAlias).
+type DeprecatedAlias struct {
+	Name      string `json:"name"`
+	Message   string `json:"message,omitempty"`
+	RemovedIn string `json:"removedIn,omitempty"`
+}
+
+// ExampleSpec describes an example command line (see Parser.HintExample).
+type ExampleSpec struct {
+	Command     string `json:"command"`
+	Description string `json:"description,omitempty"`
+}
+
+// NewFromSpec makes a Parser configured by spec.
+func NewFromSpec(spec Spec) Parser {
+	p := New()
+	p.HintSpec(spec)
+	return p
+}
+
+// HintSpec gives the parser all hints described in spec.
+func (p *Parser) HintSpec(spec Spec) {
+	if spec.NoOptionsGrouped {
+		p.HintNoOptionsGrouped()
+	}
+	if spec.DisableDoubleHyphen {
+		p.HintDisableDoubleHyphen()
+	}
+	if spec.Help {
+		p.HintHelp()
+	}
+	if spec.Version {
+		p.HintVersion()
+	}
+	if spec.EnvPrefix != "" {
+		p.HintEnvPrefix(spec.EnvPrefix)
+	}
+	if spec.Name != "" {
+		p.progName = spec.Name
+	}
+	if spec.Description != "" {
+		p.HintDescription("", spec.Description)
+	}
+	p.hintCommandSpec(nil, spec.CommandSpec)
+}
+
+func (p *Parser) hintCommandSpec(ns []string, c CommandSpec) {
+	for _, o := range c.Options {
+		p.HintOption(o.Name, ns)
+		names := append([]string{o.Name}, o.Aliases...)
+		for _, a := range o.Aliases {
+			p.HintAlias(a, o.Name, ns)
+		}
+		for _, d := range o.Deprecated {
+			p.HintDeprecatedAlias(d.Name, o.Name, d.Message, d.RemovedIn, ns)
+			names = append(names, d.Name)
+		}
+		for _, name := range names {
+			if o.WithArg {
+				p.HintWithArg(name, ns)
+			}
+			if o.LongName {
+				p.HintLongName(name, ns)
+			}
+		}
+		if o.Inherited {
+			p.HintInherited(o.Name, ns)
+		}
+		if o.Description != "" {
+			p.HintDescription(o.Name, o.Description, ns)
+		}
+		if o.Metavar != "" {
+			p.HintMetavar(o.Name, o.Metavar, ns)
+		}
+		if o.Env != "" {
+			p.HintEnv(o.Name, o.Env, ns)
+		}
+	}
+
+	for _, sub := range c.Commands {
+		p.hintSubcommand(ns, sub)
+	}
+	if c.Default != "" {
+		p.HintDefaultCommand(c.Default, ns)
+	}
+	for _, e := range c.Examples {
+		p.HintExample(e.Command, e.Description, ns)
+	}
+}
+
+// hintSubcommand gives hints of the command sub in the namespace ns.
+func (p *Parser) hintSubcommand(ns []string, sub CommandSpec) {
+	p.HintCommand(sub.Name, ns)
+	for _, a := range sub.Aliases {
+		p.HintAlias(a, sub.Name, ns)
+		p.HintCommand(a, ns)
+	}
+	for _, d := range sub.Deprecated {
+		p.HintDeprecatedAlias(d.Name, sub.Name, d.Message, d.RemovedIn, ns)
+		p.HintCommand(d.Name, ns)
+	}
+	if sub.Description != "" {
+		p.HintDescription(sub.Name, sub.Description, ns)
+	}
+
+	// hints keep their namespaces, so never share the backing array
+	subNS := make([]string, len(ns)+1)
+	copy(subNS, ns)
+	subNS[len(ns)] = sub.Name
+	p.hintCommandSpec(subNS, sub)
+}
+
+// ReadSpec reads a JSON-encoded Spec.
+// Unknown fields are errors, so that a misspelled one is not silently ignored.
+func ReadSpec(r io.Reader) (Spec, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var spec Spec
+	if err := dec.Decode(&spec); err != nil {
+		return Spec{}, err
+	}
+	return spec, nil
+}
+
+// WriteSpec writes spec in JSON.
+func WriteSpec(w io.Writer, spec Spec) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(spec)
+}
+
+// Lookup returns the command of the namespace ns, or nil if no such command is described.
+// An empty ns returns the root.
+func (c *CommandSpec) Lookup(ns []string) *CommandSpec {
+	curr := c
+	for _, name := range ns {
+		var next *CommandSpec
+		for i := range curr.Commands {
+			if curr.Commands[i].Name == name {
+				next = &curr.Commands[i]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		curr = next
+	}
+	return curr
+}
+
+// Spec returns the hints given so far as a Spec.
+// Names used as both an alias and a command are regarded as command aliases.
+func (p Parser) Spec() Spec {
+	spec := Spec{
+		CommandSpec:         CommandSpec{Name: p.ProgName()},
+		NoOptionsGrouped:    !p.optsMaybeGrouped,
+		DisableDoubleHyphen: !p.doubleHyphenEnabled,
+		Help:                p.help,
+		Version:             p.version,
+		EnvPrefix:           p.envPrefix,
+	}
+	idx := p.indexHints()
+
+	// commands first, parents before children, so that every hinted command has its CommandSpec.
+	// Namespaces without commands hinted are unreachable, and their hints are not described.
+	var cmds []hint
+	for _, h := range p.hints {
+		if h.typ == commandHint {
+			cmds = append(cmds, h)
+		}
+	}
+	sort.SliceStable(cmds, func(i, j int) bool {
+		return len(cmds[i].namespace) < len(cmds[j].namespace)
+	})
+	children := make(map[string][]string) // by the key of the parent
+	reachable := map[string]bool{pathKey(nil): true}
+	for _, h := range cmds {
+		if !reachable[pathKey(h.namespace)] {
+			continue
+		}
+		name := h.name
+		if idx.of(h.namespace).isCommandAlias(name) {
+			name = idx.of(h.namespace).physicalName(name)
+		}
+		key := pathKey(append(h.namespace[:len(h.namespace):len(h.namespace)], name))
+		if !reachable[key] {
+			reachable[key] = true
+			children[pathKey(h.namespace)] = append(children[pathKey(h.namespace)], name)
+		}
+	}
+	b := specBuilder{
+		commands: make(map[string]*CommandSpec),
+		options:  make(map[*CommandSpec]map[string]int),
+	}
+	b.build(&spec.CommandSpec, nil, children)
+
+	for _, h := range p.hints {
+		c := b.commands[pathKey(h.namespace)]
+		if c == nil {
+			continue
+		}
+		x := idx.of(h.namespace)
+		sub := func(name string) *CommandSpec {
+			return b.commands[pathKey(append(h.namespace[:len(h.namespace):len(h.namespace)], name))]
+		}
+
+		switch h.typ {
+		case aliasHint:
+			alias, name := splitAlias(h.name)
+			if x.physicalName(alias) != name {
+				continue // the first alias wins, as in parsing
+			}
+			if x.isCommandAlias(alias) {
+				if s := sub(name); s != nil {
+					s.Aliases = appendUnique(s.Aliases, alias)
+				}
+			} else {
+				o := b.option(c, name)
+				o.Aliases = appendUnique(o.Aliases, alias)
+			}
+
+		case withArgHint, longNameHint, optionHint:
+			if x.isAlias(h.name) {
+				continue
+			}
+			o := b.option(c, h.name)
+			if h.typ == withArgHint {
+				o.WithArg = true
+			} else if h.typ == longNameHint {
+				o.LongName = true
+			}
+
+		case deprecatedHint:
+			d := DeprecatedAlias{Name: h.name, Message: h.text, RemovedIn: h.removedIn}
+			name := x.physicalName(h.name)
+			if x.isCommandAlias(h.name) {
+				if s := sub(name); s != nil {
+					s.Aliases = remove(s.Aliases, h.name)
+					s.Deprecated = append(s.Deprecated, d)
+				}
+			} else {
+				o := b.option(c, name)
+				o.Aliases = remove(o.Aliases, h.name)
+				o.Deprecated = append(o.Deprecated, d)
+			}
+
+		case descriptionHint:
+			name := x.physicalName(h.name)
+			if h.name == "" {
+				c.Description = h.text
+			} else if x.isCommandOf(name) {
+				if s := sub(name); s != nil {
+					s.Description = h.text
+				}
+			} else {
+				b.option(c, name).Description = h.text
+			}
+
+		case envHint:
+			if o := b.option(c, x.physicalName(h.name)); o.Env == "" {
+				o.Env = h.text
+			}
+
+		case exampleHint:
+			c.Examples = append(c.Examples, ExampleSpec{Command: h.name, Description: h.text})
+
+		case metavarHint:
+			b.option(c, x.physicalName(h.name)).Metavar = h.text
+
+		case defaultCommandHint:
+			if c.Default == "" {
+				c.Default = h.name
+			}
+
+		case inheritedHint:
+			b.option(c, x.physicalName(h.name)).Inherited = true
+		}
+	}
+
+	return spec
+}
+
+// specBuilder indexes the commands and the options of a Spec being built.
+type specBuilder struct {
+	commands map[string]*CommandSpec // by the keys of the namespaces
+	options  map[*CommandSpec]map[string]int
+}
+
+// build gives c the children of the namespace ns, and indexes them.
+// Commands are never appended afterwards, so that the pointers are stable.
+func (b *specBuilder) build(c *CommandSpec, ns []string, children map[string][]string) {
+	key := pathKey(ns)
+	b.commands[key] = c
+	for _, name := range children[key] {
+		c.Commands = append(c.Commands, CommandSpec{Name: name})
+	}
+	for i := range c.Commands {
+		b.build(&c.Commands[i], append(ns[:len(ns):len(ns)], c.Commands[i].Name), children)
+	}
+}
+
+// option returns the option name of c, adding it if not found.
+func (b *specBuilder) option(c *CommandSpec, name string) *OptionSpec {
+	opts := b.options[c]
+	if opts == nil {
+		opts = make(map[string]int)
+		b.options[c] = opts
+	}
+	if i, found := opts[name]; found {
+		return &c.Options[i]
+	}
+	opts[name] = len(c.Options)
+	c.Options = append(c.Options, OptionSpec{Name: name})
+	return &c.Options[len(c.Options)-1]
+}
+
+// hintIndex is aliases and commands of hints by the keys of the namespaces.
+type hintIndex map[string]*nsHints
+
+type nsHints struct {
+	aliases   map[string]string // the first alias wins, as in parsing
+	commands  map[string]bool   // names given by HintCommand
+	commandOf map[string]bool   // physical names of commands
+}
+
+func (p Parser) indexHints() hintIndex {
+	idx := make(hintIndex)
+	for _, h := range p.hints {
+		if h.typ != aliasHint && h.typ != commandHint {
+			continue
+		}
+		key := pathKey(h.namespace)
+		x := idx[key]
+		if x == nil {
+			x = &nsHints{aliases: make(map[string]string), commands: make(map[string]bool), commandOf: make(map[string]bool)}
+			idx[key] = x
+		}
+		if h.typ == commandHint {
+			x.commands[h.name] = true
+		} else if alias, name := splitAlias(h.name); !x.isAlias(alias) {
+			x.aliases[alias] = name
+		}
+	}
+	for _, x := range idx {
+		// namespaces consist of physical names, not aliases
+		for name := range x.commands {
+			if !x.isAlias(name) {
+				x.commandOf[name] = true
+			} else {
+				x.commandOf[x.aliases[name]] = true
+			}
+		}
+	}
+	return idx
+}
+
+// of returns the hints of ns, which may be empty.
+func (idx hintIndex) of(ns []string) *nsHints {
+	if x := idx[pathKey(ns)]; x != nil {
+		return x
+	}
+	return &nsHints{}
+}
+
+func (x *nsHints) isAlias(name string) bool {
+	_, found := x.aliases[name]
+	return found
+}
+
+// physicalName returns the name that the alias points to.
+func (x *nsHints) physicalName(alias string) string {
+	if name, found := x.aliases[alias]; found {
+		return name
+	}
+	return alias
+}
+
+func (x *nsHints) isCommandAlias(name string) bool {
+	physical, found := x.aliases[name]
+	return found && (x.commands[name] || x.commands[physical])
+}
+
+// isCommandOf reports whether the physical name is a command.
+func (x *nsHints) isCommandOf(name string) bool {
+	return x.commandOf[name]
+}
+
+func splitAlias(s string) (alias, name string) {
+	i := strings.Index(s, ":")
+	if i == -1 {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
+
+func equalNS(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func remove(list []string, s string) []string {
+	var removed []string
+	for _, e := range list {
+		if e != s {
+			removed = append(removed, e)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,186 @@
+package cliparser_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+const specJSON = `{
+  "name": "tool",
+  "options": [
+    {"name": "opt1", "aliases": ["go"], "longName": true}
+  ],
+  "commands": [
+    {
+      "name": "cmd",
+      "aliases": ["c"],
+      "options": [
+        {"name": "opt2", "aliases": ["co"], "withArg": true, "longName": true}
+      ],
+      "commands": [
+        {"name": "sub"}
+      ]
+    }
+  ]
+}`
+
+func TestSpec(t *testing.T) {
+	t.Run("Read", func(t *testing.T) {
+		spec, err := cliparser.ReadSpec(strings.NewReader(specJSON))
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, spec.Name, "tool")
+		gotwant.Test(t, len(spec.Commands), 1)
+		gotwant.Test(t, spec.Commands[0].Options[0], cliparser.OptionSpec{Name: "opt2", Aliases: []string{"co"}, WithArg: true, LongName: true})
+
+		_, err = cliparser.ReadSpec(strings.NewReader(`{"name":`))
+		gotwant.TestError(t, err, "unexpected EOF")
+
+		_, err = cliparser.ReadSpec(strings.NewReader(`{"options": [{"name": "n", "withArgs": true}]}`))
+		gotwant.TestError(t, err, `unknown field "withArgs"`)
+	})
+
+	t.Run("NewFromSpec", func(t *testing.T) {
+		spec, err := cliparser.ReadSpec(strings.NewReader(specJSON))
+		gotwant.TestError(t, err, nil)
+
+		p := cliparser.NewFromSpec(spec)
+		p.Feed([]string{"-go", "c", "-co", "aaa", "sub", "bbb"})
+
+		err = p.Parse()
+		gotwant.TestError(t, err, nil)
+
+		c := p.GetComponent()
+		gotwant.Test(t, c, &cliparser.Component{Type: cliparser.Option, Name: "opt1", Arg: "true"})
+		c = p.GetComponent()
+		gotwant.Test(t, c, &cliparser.Component{Type: cliparser.Command, Name: "cmd"})
+		c = p.GetComponent()
+		gotwant.Test(t, c, &cliparser.Component{Type: cliparser.Option, Name: "opt2", Arg: "aaa"})
+		c = p.GetComponent()
+		gotwant.Test(t, c, &cliparser.Component{Type: cliparser.Command, Name: "sub"})
+		c = p.GetComponent()
+		gotwant.Test(t, c, &cliparser.Component{Type: cliparser.Arg, Arg: "bbb"})
+	})
+
+	t.Run("Modes", func(t *testing.T) {
+		p := cliparser.NewFromSpec(cliparser.Spec{NoOptionsGrouped: true, DisableDoubleHyphen: true})
+		p.Feed([]string{"-abc", "--", "-d"})
+
+		err := p.Parse()
+		gotwant.TestError(t, err, nil)
+
+		c := p.GetComponent()
+		gotwant.Test(t, c, &cliparser.Component{Type: cliparser.Option, Name: "abc", Arg: "true"})
+		c = p.GetComponent()
+		gotwant.Test(t, c, &cliparser.Component{Type: cliparser.Arg, Arg: "--"})
+	})
+
+	t.Run("Write", func(t *testing.T) {
+		spec := cliparser.Spec{
+			CommandSpec: cliparser.CommandSpec{
+				Name:    "tool",
+				Options: []cliparser.OptionSpec{{Name: "v"}},
+				Commands: []cliparser.CommandSpec{
+					{Name: "run", Options: []cliparser.OptionSpec{{Name: "n", WithArg: true}}},
+				},
+			},
+			NoOptionsGrouped: true,
+		}
+
+		var buf bytes.Buffer
+		err := cliparser.WriteSpec(&buf, spec)
+		gotwant.TestError(t, err, nil)
+
+		read, err := cliparser.ReadSpec(&buf)
+		gotwant.TestError(t, err, nil)
+		gotwant.Test(t, read, spec)
+	})
+
+	t.Run("Introspect", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintAlias("go", "opt1")
+		p.HintLongName("go")
+		p.HintLongName("opt1")
+		p.HintAlias("c", "cmd")
+		p.HintCommand("c")
+		p.HintCommand("cmd")
+		p.HintAlias("co", "opt2", []string{"cmd"})
+		p.HintLongName("co", []string{"cmd"})
+		p.HintLongName("opt2", []string{"cmd"})
+		p.HintWithArg("opt2", []string{"cmd"})
+		p.HintCommand("sub", []string{"cmd"})
+		p.HintOption("v", []string{"cmd", "sub"})
+		p.HintWithArg("x", []string{"ghost"})
+
+		spec := p.Spec()
+		gotwant.Test(t, spec, cliparser.Spec{
+			CommandSpec: cliparser.CommandSpec{
+				Options: []cliparser.OptionSpec{{Name: "opt1", Aliases: []string{"go"}, LongName: true}},
+				Commands: []cliparser.CommandSpec{
+					{
+						Name:     "cmd",
+						Aliases:  []string{"c"},
+						Options:  []cliparser.OptionSpec{{Name: "opt2", Aliases: []string{"co"}, WithArg: true, LongName: true}},
+						Commands: []cliparser.CommandSpec{{Name: "sub", Options: []cliparser.OptionSpec{{Name: "v"}}}},
+					},
+				},
+			},
+		})
+
+		gotwant.Test(t, spec.Lookup(nil).Name, "")
+		gotwant.Test(t, spec.Lookup([]string{"cmd", "sub"}).Options[0].Name, "v")
+		gotwant.Test(t, spec.Lookup([]string{"cmd", "none"}), (*cliparser.CommandSpec)(nil))
+
+		// ghost is not a command, and a round trip keeps it an arg
+		p.Feed([]string{"ghost"})
+		gotwant.TestError(t, p.Parse(), nil)
+		gotwant.Test(t, p.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "ghost"})
+		p2 := cliparser.NewFromSpec(spec)
+		p2.Feed([]string{"ghost"})
+		gotwant.TestError(t, p2.Parse(), nil)
+		gotwant.Test(t, p2.GetComponent(), &cliparser.Component{Type: cliparser.Arg, Arg: "ghost"})
+	})
+
+	t.Run("DuplicateAlias", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("delete")
+		p.HintAlias("rm", "remove")
+		p.HintAlias("rm", "delete")
+		p.HintCommand("d")
+		p.HintAlias("d", "delete")
+		p.HintAlias("d", "other")
+
+		// the first alias wins, as in parsing
+		gotwant.Test(t, p.Spec().CommandSpec, cliparser.CommandSpec{
+			Options:  []cliparser.OptionSpec{{Name: "remove", Aliases: []string{"rm"}}},
+			Commands: []cliparser.CommandSpec{{Name: "delete", Aliases: []string{"d"}}},
+		})
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		spec, err := cliparser.ReadSpec(strings.NewReader(specJSON))
+		gotwant.TestError(t, err, nil)
+
+		p := cliparser.NewFromSpec(spec)
+		gotwant.Test(t, p.Spec(), spec)
+	})
+}
+
+func BenchmarkSpec(b *testing.B) {
+	p := cliparser.New()
+	for i := 0; i < 2000; i++ {
+		p.HintCommand(fmt.Sprintf("sub%d", i))
+		p.HintAlias(fmt.Sprintf("s%d", i), fmt.Sprintf("sub%d", i))
+		p.HintCommand(fmt.Sprintf("s%d", i))
+		p.HintWithArg(fmt.Sprintf("o%d", i), []string{fmt.Sprintf("sub%d", i)})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Spec()
+	}
+}
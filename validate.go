@@ -0,0 +1,220 @@
+package cliparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HintError is a problem of hints found by Parser.Validate.
+type HintError struct {
+	Namespace []string
+	Name      string
+	Problem   string
+}
+
+func (e HintError) Error() string {
+	return fmt.Sprintf("namespace %v: %q %s", e.Namespace, e.Name, e.Problem)
+}
+
+// HintErrors is a list of HintError.
+type HintErrors []HintError
+
+func (e HintErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, he := range e {
+		msgs = append(msgs, he.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks conflicts of hints and reports all problems at once as HintErrors.
+//
+// The problems are:
+//   - a name is both a command and an option in a namespace
+//   - an alias is defined twice for different names
+//   - aliases make a cycle, or an alias points to another alias
+//   - an alias of a command is not a command, or vice versa
+//   - a command or an alias shadows an option inherited from an ancestor namespace (see HintInherited and Everywhere),
+//     which makes the option unavailable there
+//   - hints are given for a namespace unreachable by commands
+func (p Parser) Validate() error {
+	idx := p.indexHints()
+
+	var errs HintErrors
+	add := func(ns []string, name, format string, args ...interface{}) {
+		errs = append(errs, HintError{Namespace: ns, Name: name, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	for _, ns := range p.namespaces() {
+		var commands, options []string
+		aliases := make(map[string]string)
+		var aliasOrder []string
+
+		for _, h := range p.hints {
+			if !equalNS(h.namespace, ns) {
+				continue
+			}
+			switch h.typ {
+			case commandHint:
+				commands = appendUnique(commands, h.name)
+			case withArgHint, longNameHint, optionHint:
+				options = appendUnique(options, h.name)
+			case aliasHint:
+				alias, name := splitAlias(h.name)
+				if prev, found := aliases[alias]; found {
+					if prev != name {
+						add(ns, alias, "is an alias of both %q and %q", prev, name)
+					}
+					continue
+				}
+				aliases[alias] = name
+				aliasOrder = append(aliasOrder, alias)
+			}
+		}
+
+		for _, name := range commands {
+			if contains(options, name) {
+				add(ns, name, "is both a command and an option")
+			}
+		}
+
+		for _, alias := range aliasOrder {
+			name := aliases[alias]
+
+			if _, found := aliases[name]; found {
+				if cycle := aliasCycle(aliases, alias); cycle != nil {
+					// report a cycle once, from its least member
+					if alias == leastOf(cycle) {
+						add(ns, alias, "makes an alias cycle %s", strings.Join(append(cycle, alias), " -> "))
+					}
+				} else {
+					add(ns, alias, "is an alias of another alias %q", name)
+				}
+				continue
+			}
+
+			aliasIsCmd, nameIsCmd := contains(commands, alias), contains(commands, name)
+			if aliasIsCmd && !nameIsCmd {
+				add(ns, alias, "is a command but its name %q is not", name)
+			} else if !aliasIsCmd && nameIsCmd {
+				add(ns, alias, "is not a command but its name %q is", name)
+			}
+		}
+
+		inherited := p.inheritedOptions(idx, ns)
+		for _, name := range commands {
+			if o, found := inherited[name]; found {
+				add(ns, name, "is a command that shadows the option %q inherited from namespace %v", o.name, o.ns)
+			}
+		}
+		for _, alias := range aliasOrder {
+			if o, found := inherited[alias]; found && o.name != aliases[alias] && !contains(commands, alias) {
+				add(ns, alias, "is an alias of %q that shadows the option %q inherited from namespace %v", aliases[alias], o.name, o.ns)
+			}
+		}
+	}
+
+	for _, ns := range p.namespaces() {
+		for i := range ns {
+			if !idx.of(ns[:i]).isCommandOf(ns[i]) {
+				add(ns, ns[i], "is not a command of namespace %v, so the namespace is unreachable", ns[:i])
+				break
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// namespaces returns all namespaces of hints in order of appearance.
+func (p Parser) namespaces() [][]string {
+	nss := [][]string{nil}
+	for _, h := range p.hints {
+		found := false
+		for _, ns := range nss {
+			if equalNS(ns, h.namespace) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			nss = append(nss, h.namespace)
+		}
+	}
+	return nss
+}
+
+type inheritedOption struct {
+	name string // physical
+	ns   []string
+}
+
+// inheritedOptions returns the options inherited by ns from its ancestors, by their names and aliases.
+// Nearer ancestors take precedence.
+func (p Parser) inheritedOptions(idx hintIndex, ns []string) map[string]inheritedOption {
+	inherited := make(map[string]inheritedOption)
+	for i := len(ns) - 1; i >= 0; i-- {
+		anc := ns[:i]
+		for _, h := range p.hints {
+			if h.typ != inheritedHint || !equalNS(h.namespace, anc) {
+				continue
+			}
+			o := inheritedOption{name: idx.of(anc).physicalName(h.name), ns: anc}
+			names := []string{o.name}
+			for _, a := range p.hints {
+				if a.typ != aliasHint || !equalNS(a.namespace, anc) {
+					continue
+				}
+				if alias, name := splitAlias(a.name); name == o.name {
+					names = append(names, alias)
+				}
+			}
+			for _, name := range names {
+				if _, found := inherited[name]; !found {
+					inherited[name] = o
+				}
+			}
+		}
+	}
+	return inherited
+}
+
+// aliasCycle returns the chain of aliases from alias if it comes back to alias, or nil.
+func aliasCycle(aliases map[string]string, alias string) []string {
+	chain := []string{alias}
+	curr := alias
+	for i := 0; i < len(aliases); i++ {
+		next, found := aliases[curr]
+		if !found {
+			return nil
+		}
+		if next == alias {
+			return chain
+		}
+		chain = append(chain, next)
+		curr = next
+	}
+	return nil
+}
+
+func leastOf(list []string) string {
+	least := list[0]
+	for _, s := range list[1:] {
+		if s < least {
+			least = s
+		}
+	}
+	return least
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package cliparser_test
+
+import (
+	"testing"
+
+	"github.com/shu-go/cliparser"
+	"github.com/shu-go/gotwant"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintAlias("go", "opt1")
+		p.HintLongName("go")
+		p.HintLongName("opt1")
+		p.HintAlias("c", "cmd")
+		p.HintCommand("c")
+		p.HintCommand("cmd")
+		p.HintAlias("co", "opt2", []string{"cmd"})
+		p.HintWithArg("opt2", []string{"cmd"})
+
+		gotwant.TestError(t, p.Validate(), nil)
+	})
+
+	t.Run("CommandAndOption", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("sub")
+		p.HintWithArg("sub")
+		p.HintWithArg("sub", []string{"sub"})
+
+		err := p.Validate()
+		gotwant.Test(t, err, cliparser.HintErrors{
+			{Namespace: nil, Name: "sub", Problem: "is both a command and an option"},
+		})
+	})
+
+	t.Run("AliasCycle", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintAlias("a", "b")
+		p.HintAlias("b", "a")
+		p.HintAlias("x", "y")
+		p.HintAlias("y", "z")
+
+		err := p.Validate()
+		gotwant.Test(t, err, cliparser.HintErrors{
+			{Name: "a", Problem: "makes an alias cycle a -> b -> a"},
+			{Name: "x", Problem: `is an alias of another alias "y"`},
+		})
+		gotwant.TestError(t, err, "alias cycle")
+	})
+
+	t.Run("DuplicateAlias", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintAlias("a", "opt1")
+		p.HintAlias("a", "opt1")
+		p.HintAlias("a", "opt2")
+
+		err := p.Validate()
+		gotwant.Test(t, err, cliparser.HintErrors{
+			{Name: "a", Problem: `is an alias of both "opt1" and "opt2"`},
+		})
+	})
+
+	t.Run("AliasCommand", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintAlias("c", "cmd")
+		p.HintCommand("cmd")
+		p.HintAlias("o", "opt")
+		p.HintCommand("o")
+
+		err := p.Validate()
+		gotwant.Test(t, err, cliparser.HintErrors{
+			{Name: "c", Problem: `is not a command but its name "cmd" is`},
+			{Name: "o", Problem: `is a command but its name "opt" is not`},
+		})
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("sub")
+		p.HintAlias("r", "remote")
+		p.HintCommand("r")
+		p.HintCommand("remote")
+		p.HintWithArg("b", []string{"sub"})
+		p.HintWithArg("b", []string{"remote"})
+		p.HintWithArg("b", []string{"r"})
+		p.HintWithArg("b", []string{"sub", "subsub"})
+
+		err := p.Validate()
+		gotwant.Test(t, err, cliparser.HintErrors{
+			{Namespace: []string{"r"}, Name: "r", Problem: "is not a command of namespace [], so the namespace is unreachable"},
+			{Namespace: []string{"sub", "subsub"}, Name: "subsub", Problem: "is not a command of namespace [sub], so the namespace is unreachable"},
+		})
+	})
+	t.Run("Shadowing", func(t *testing.T) {
+		p := cliparser.New()
+		p.HintCommand("remote")
+		p.HintCommand("add", []string{"remote"})
+		p.HintWithArg("C")
+		p.HintInherited("C")
+		p.HintAlias("v", "verbose", cliparser.Everywhere)
+		p.HintWithArg("C", []string{"remote"}) // overriding is not shadowing
+		p.HintCommand("C", []string{"remote", "add"})
+		p.HintAlias("v", "version", []string{"remote"})
+
+		err := p.Validate()
+		gotwant.Test(t, err, cliparser.HintErrors{
+			{Namespace: []string{"remote"}, Name: "v", Problem: `is an alias of "version" that shadows the option "verbose" inherited from namespace []`},
+			{Namespace: []string{"remote", "add"}, Name: "C", Problem: `is a command that shadows the option "C" inherited from namespace []`},
+		})
+	})
+}